@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	accrualmock "github.com/ShvetsovYura/oygophermart/internal/accrual_mock"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	var addr, config string
+	var opts accrualmock.Options
+	flag.StringVar(&addr, "a", ":8080", "server endpoint address")
+	flag.StringVar(&config, "config", "", "JSON file with scenarios and prefixes")
+	flag.StringVar(&opts.Default, "default", "processed", "scenario for orders without matching prefix")
+	flag.DurationVar(&opts.Latency, "latency", 0, "response latency")
+	flag.DurationVar(&opts.Jitter, "jitter", 0, "random addition to latency")
+	flag.Float64Var(&opts.ErrorRate, "error-rate", 0, "share of requests answered with 500")
+	flag.IntVar(&opts.RPS, "rps", 0, "requests per second before 429, 0 - unlimited")
+	flag.IntVar(&opts.RetryAfter, "retry-after", 1, "Retry-After seconds for 429")
+	flag.Parse()
+
+	if config != "" {
+		data, err := os.ReadFile(config)
+		if err != nil {
+			fmt.Println(err)
+			return 2
+		}
+		if err = json.Unmarshal(data, &opts); err != nil {
+			fmt.Println(err)
+			return 2
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	mock, err := accrualmock.NewServer(opts)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	srv := &http.Server{Addr: addr, Handler: mock.Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	fmt.Printf("accrual mock on %s\n", addr)
+	if err = srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Println(err)
+		return 1
+	}
+	return 0
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"os/signal"
 	"syscall"
 
@@ -12,6 +13,10 @@ import (
 )
 
 func main() {
+	os.Exit(run())
+}
+
+func run() int {
 	logger.InitLogger("info")
 
 	opt := options.AppOptions{}
@@ -19,13 +24,15 @@ func main() {
 	err := opt.ParseEnv()
 	if err != nil {
 		fmt.Println(err)
+		return 2
 	}
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
 	err = app.Run(ctx, &opt)
 	if err != nil {
-		logger.Log.Fatalf("Error on run: %e", err)
+		logger.Log.Errorf("Error on run: %v", err)
+		return 1
 	}
-	<-ctx.Done()
+	return 0
 }
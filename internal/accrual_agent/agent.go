@@ -2,51 +2,84 @@ package accrualagent
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ShvetsovYura/oygophermart/internal/logger"
 	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/options"
 	"github.com/ShvetsovYura/oygophermart/internal/utils"
-	"github.com/dghubble/sling"
-	"golang.org/x/sync/errgroup"
 )
 
+//go:generate mockgen -destination=../../mocks/mock_saver.go -package=mocks github.com/ShvetsovYura/oygophermart/internal/accrual_agent Saver
 type Saver interface {
-	GetOrdersToAccrualProcess(ctx context.Context) ([]models.OrderModel, error)
+	ClaimAccrualJobs(ctx context.Context, limit int, lease time.Duration) ([]models.AccrualJobModel, error)
+	FailAccrualJobs(ctx context.Context, failures ...models.AccrualJobFailure) error
 	UpdateOrdersStatus(context.Context, ...models.AccrualResult) error
+	AssignOrdersPartner(ctx context.Context, partner string, orderIDs ...string) error
+}
+
+type accrualJob struct {
+	orderID string
+	partner *partner
+	// неудачных опросов подряд до текущего
+	attempts  int
+	createdAt time.Time
 }
 
 type TaskResult struct {
+	job  accrualJob
 	data *models.AccrualResult
 	err  error
+	// опрос прерван отменой или дедлайном контекста агента, а не ошибкой партнера
+	interrupted bool
 }
 
 type AccrualAgent struct {
-	accrualLink       string
-	saveService       Saver
-	workers           int
-	taskResultCh      chan TaskResult
-	accrualTicker     *time.Ticker
-	defaultRetryAfter int
+	partners      *partnerRouter
+	saveService   Saver
+	workers       int
+	batchSize     int
+	jobLease      time.Duration
+	retryPolicy   RetryPolicy
+	taskResultCh  chan TaskResult
+	interval      time.Duration
+	accrualTicker *time.Ticker
+	// число неудачных попыток подряд забрать задания из БД
+	dbFailures atomic.Int32
+	// число неудачных попыток подряд сохранить результаты, только для горутины записи
+	flushFailures int
 }
 
-func NewAccrualAgent(accrualAddr string, service Saver, interval uint) *AccrualAgent {
+const (
+	maxDBRetry   = time.Minute
+	flushTimeout = 10 * time.Second
+	// после стольких неудачных попыток сохранения пачка отбрасывается
+	maxFlushAttempts = 5
+)
+
+// newClient создает клиентов партнеров; nil - HTTP-клиент NewHTTPAccrualClient
+func NewAccrualAgent(partners []options.PartnerOptions, newClient ClientFactory, service Saver, interval uint, retryPolicy RetryPolicy) (*AccrualAgent, error) {
+	router, err := newPartnerRouter(partners, newClient)
+	if err != nil {
+		return nil, err
+	}
 	instance := &AccrualAgent{
-		accrualLink:       accrualAddr,
-		saveService:       service,
-		workers:           3,
-		taskResultCh:      make(chan TaskResult, 1000),
-		accrualTicker:     time.NewTicker(time.Duration(interval) * time.Second),
-		defaultRetryAfter: 5,
+		partners:      router,
+		saveService:   service,
+		workers:       3,
+		batchSize:     100,
+		jobLease:      time.Minute,
+		retryPolicy:   retryPolicy,
+		taskResultCh:  make(chan TaskResult, 1000),
+		interval:      time.Duration(interval) * time.Second,
+		accrualTicker: time.NewTicker(time.Duration(interval) * time.Second),
 	}
 
-	return instance
+	return instance, nil
 }
 
 var ErrOrderNotRegistered = errors.New("order not registered in accrual system")
@@ -70,150 +103,263 @@ func NewTooManyRequestsError(err error, retryAfter int) *TooManyRequestsError {
 	}
 }
 
+// Start блокирует до отмены ctx. После отмены дожидается текущего опроса
+// и сохраняет накопленные результаты.
 func (a *AccrualAgent) Start(ctx context.Context) {
-	go a.startProccessFlushAccrual(ctx)
-	go a.startAccrualProcess(ctx)
-	<-ctx.Done()
+	flushed := make(chan struct{})
+	go func() {
+		a.startProccessFlushAccrual()
+		close(flushed)
+	}()
+	a.startAccrualProcess(ctx)
+	close(a.taskResultCh)
+	<-flushed
 }
 
-func (a *AccrualAgent) getAccrualStatusRequest(orderID string) (*models.AccrualResult, error) {
-	s := sling.New().Base(a.accrualLink).Set("User-Agent", "OyGopherMart client")
-	r, err := s.New().Get("/api/orders/" + orderID).Request()
+// LimiterStates возвращает текущее состояние ограничителей запросов к партнерам
+func (a *AccrualAgent) LimiterStates() []models.LimiterStateResp {
+	return a.partners.limiterStates()
+}
 
-	if err != nil {
-		logger.Log.Debug("error on create request")
-		return nil, err
+// Health возвращает состояние очереди заданий и цепей запросов к партнерам.
+// Доступность БД проверяет сам обработчик health, агент ее не заполняет.
+func (a *AccrualAgent) Health() models.HealthResp {
+	result := models.HealthResp{
+		Status:   models.HealthOK,
+		Partners: a.partners.breakerStates(),
 	}
-	r.Header.Add("Content-Type", "application/json")
-	client := http.Client{}
-	resp, err := client.Do(r)
-	if err != nil {
-		logger.Log.Debug("error on Do request")
-		return nil, err
+	if a.dbFailures.Load() > 0 {
+		result.Status = models.HealthDegraded
 	}
-	switch resp.StatusCode {
-	case http.StatusOK:
-		logger.Log.Debug("Succes get status")
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-
-		var result models.AccrualResult
-		err = json.Unmarshal(body, &result)
-		if err != nil {
-			return nil, err
+	for _, p := range result.Partners {
+		if p.State != breakerClosed {
+			result.Status = models.HealthDegraded
 		}
+	}
+	return result
+}
 
-		return &result, nil
-	case http.StatusNoContent:
-		return nil, ErrOrderNotRegistered
-	case http.StatusTooManyRequests:
-		var retryafter int
-		retryHeder := resp.Header.Get("Retry-After")
-		retryafter, err := strconv.Atoi(retryHeder)
-		if err != nil {
-			retryafter = a.defaultRetryAfter
-		}
-		logger.Log.Debugf("Too many requests, retryafter: %d", retryafter)
-		return nil, NewTooManyRequestsError(ErrTooManyIntegrationRequests, retryafter)
-	case http.StatusInternalServerError:
-		return nil, errors.New("server error on make request")
+func (a *AccrualAgent) getAccrualStatusRequest(ctx context.Context, p *partner, orderID string) (*models.AccrualResult, error) {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	if ok, _ := p.breaker.Allow(); !ok {
+		return nil, ErrCircuitOpen
+	}
+	result, err := p.client.GetOrder(ctx, orderID)
+	switch {
+	case ctx.Err() != nil:
+		// запрос оборвал сам агент - о доступности партнера это ничего не говорит
+		p.breaker.Release()
+	case errors.Is(err, ErrAccrualUnavailable):
+		p.breaker.Failure()
 	default:
-		return nil, errors.New("not success")
+		p.breaker.Success()
 	}
 
+	var target *TooManyRequestsError
+	switch {
+	case err == nil, errors.Is(err, ErrOrderNotRegistered):
+		p.limiter.Success()
+	case errors.As(err, &target):
+		logger.Log.Debugf("Too many requests, retryafter: %d", target.RetryAfter)
+		// останавливаем запросы всех воркеров к этому партнеру
+		p.limiter.Throttle(time.Duration(target.RetryAfter) * time.Second)
+	default:
+		logger.Log.Debugf("error on accrual request %s: %v", orderID, err)
+	}
+	return result, err
 }
 
-func (a *AccrualAgent) accrualWorker(orders <-chan string) error {
-	for oid := range orders {
-		resp, err := a.getAccrualStatusRequest(oid)
-		if err != nil {
-			if errors.Is(err, ErrTooManyIntegrationRequests) {
-				return err
-			}
+func (a *AccrualAgent) accrualWorker(ctx context.Context, jobs <-chan accrualJob) {
+	for job := range jobs {
+		resp, err := a.getAccrualStatusRequest(ctx, job.partner, job.orderID)
+		if resp != nil {
+			resp.Source = models.StatusSourcePoll
+			resp.NextPoll = a.retryPolicy.pollDelay()
 		}
 		a.taskResultCh <- TaskResult{
-			data: resp,
-			err:  err,
+			job:         job,
+			data:        resp,
+			err:         err,
+			interrupted: err != nil && ctx.Err() != nil,
 		}
 	}
-	return nil
 }
 
-func (a *AccrualAgent) getAccrualInfoTick() {
-	eg := &errgroup.Group{}
+func (a *AccrualAgent) getAccrualInfoTick(parent context.Context) {
 	logger.Log.Debug("start tick func")
-	// взять заказы из БД и отправить номера в WorkerPool
-	dbOrders, err := a.saveService.GetOrdersToAccrualProcess(context.Background())
+	// взять задания из очереди и отправить номера в WorkerPool
+	dbJobs, err := a.saveService.ClaimAccrualJobs(parent, a.batchSize, a.jobLease)
+	if err != nil && parent.Err() != nil {
+		return
+	}
 	if err != nil {
-		logger.Log.Fatal(err)
+		// БД недоступна - повторяем с растущей паузой
+		failures := a.dbFailures.Add(1)
+		retry := a.interval << min(failures, 16)
+		if retry > maxDBRetry {
+			retry = maxDBRetry
+		}
+		logger.Log.Errorf("error on claim accrual jobs, retry after %s: %v", retry, err)
+		a.accrualTicker.Reset(retry)
+		return
+	}
+	if a.dbFailures.Swap(0) > 0 {
+		a.accrualTicker.Reset(a.interval)
 	}
+	// опрос должен уложиться в аренду заданий, иначе их заберет другой экземпляр;
+	// задания, которые не дождались ограничителя, возвращаются в очередь с его задержкой
+	ctx, cancel := context.WithTimeout(parent, a.jobLease)
+	defer cancel()
+
 	// номера заказов в канал
-	ordersToCheckCh := make(chan string, len(dbOrders))
+	ordersToCheckCh := make(chan accrualJob, len(dbJobs))
+	jobs := a.routeOrders(dbJobs)
 
+	wg := &sync.WaitGroup{}
 	for w := 0; w < a.workers; w++ {
-		eg.Go(func() error {
-			err := a.accrualWorker(ordersToCheckCh)
-			if err != nil {
-				return err
-			}
-			return nil
-		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.accrualWorker(ctx, ordersToCheckCh)
+		}()
 	}
 
 	// отправка заказов в канал -
-	for _, order := range dbOrders {
-		ordersToCheckCh <- order.ID
+	for _, job := range jobs {
+		ordersToCheckCh <- job
 	}
 	close(ordersToCheckCh)
+	wg.Wait()
+}
 
-	if err = eg.Wait(); err != nil {
-		var target *TooManyRequestsError
-		if errors.As(err, &target) {
-
-			logger.Log.Debug("Too many requests")
-			a.accrualTicker.Reset(time.Duration(target.RetryAfter * int(time.Second)))
+// routeOrders определяет партнера для каждого заказа и сохраняет его для новых заказов
+func (a *AccrualAgent) routeOrders(orders []models.AccrualJobModel) []accrualJob {
+	jobs := make([]accrualJob, 0, len(orders))
+	unassigned := make(map[string][]string)
+	for _, order := range orders {
+		var p *partner
+		if order.Partner != "" {
+			p = a.partners.byName(order.Partner, order.OrderID)
+		} else {
+			p = a.partners.route(order.OrderID)
+			unassigned[p.opts.Name] = append(unassigned[p.opts.Name], order.OrderID)
 		}
+		jobs = append(jobs, accrualJob{
+			orderID:   order.OrderID,
+			partner:   p,
+			attempts:  order.Attempts,
+			createdAt: order.CreatedAt,
+		})
 	}
+	for name, orderIDs := range unassigned {
+		err := a.saveService.AssignOrdersPartner(context.Background(), name, orderIDs...)
+		if err != nil {
+			logger.Log.Debugf("error on assign partner %s: %v", name, err)
+		}
+	}
+	return jobs
 }
 
-func (a *AccrualAgent) startProccessFlushAccrual(ctx context.Context) {
+func (a *AccrualAgent) startProccessFlushAccrual() {
 	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
 	var records []models.AccrualResult
+	var failures []models.AccrualJobFailure
 	for {
 		select {
-		case rec := <-a.taskResultCh:
+		case rec, ok := <-a.taskResultCh:
+			if !ok {
+				// канал закрыт при остановке - сохраняем остаток
+				ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+				a.flush(ctx, &records, &failures)
+				cancel()
+				logger.Log.Debug("flush accrual ended")
+				return
+			}
 			if rec.data != nil {
 				if !utils.Contains(*rec.data, records) {
 					records = append(records, *rec.data)
 				}
+			} else if rec.err != nil && !rec.interrupted {
+				// прерванный при остановке опрос не считается попыткой:
+				// задание вернется в очередь по истечении аренды
+				failures = append(failures, a.jobFailure(rec))
 			}
 		case <-ticker.C:
-			if len(records) == 0 {
-				logger.Log.Debug("No record to write")
-				continue
-			}
-			err := a.saveService.UpdateOrdersStatus(ctx, records...)
-			if err != nil {
-				logger.Log.Debug(err)
-				continue
-			}
-			records = nil
-		case <-ctx.Done():
-			logger.Log.Debug("flush accrual ended")
-			return
+			a.flush(context.Background(), &records, &failures)
 		}
 	}
 }
 
+func (a *AccrualAgent) flush(ctx context.Context, records *[]models.AccrualResult, failures *[]models.AccrualJobFailure) {
+	if len(*failures) > 0 {
+		err := a.saveService.FailAccrualJobs(ctx, *failures...)
+		if err != nil {
+			logger.Log.Debug(err)
+		} else {
+			*failures = nil
+		}
+	}
+	if len(*records) == 0 {
+		logger.Log.Debug("No record to write")
+		return
+	}
+	// пачка применяется одной транзакцией: при ошибке она повторяется целиком.
+	// Если ошибка не проходит, пачка отбрасывается - задания остались в очереди
+	// и будут опрошены снова после истечения аренды
+	err := a.saveService.UpdateOrdersStatus(ctx, *records...)
+	if err != nil {
+		a.flushFailures++
+		logger.Log.Errorf("error on apply accrual results (attempt %d): %v", a.flushFailures, err)
+		if a.flushFailures >= maxFlushAttempts {
+			*records = nil
+			a.flushFailures = 0
+		}
+		return
+	}
+	*records = nil
+	a.flushFailures = 0
+}
+
+// jobFailure определяет, через сколько повторить опрос заказа после ошибки
+// и не пора ли прекратить опрос
+func (a *AccrualAgent) jobFailure(rec TaskResult) models.AccrualJobFailure {
+	failure := models.AccrualJobFailure{
+		OrderID: rec.job.orderID,
+		Error:   rec.err.Error(),
+	}
+	var target *TooManyRequestsError
+	if errors.As(rec.err, &target) {
+		// ограничение партнера не считается неудачей заказа
+		failure.RetryAfter = time.Duration(target.RetryAfter) * time.Second
+		failure.Postponed = true
+		return failure
+	}
+	if errors.Is(rec.err, ErrCircuitOpen) {
+		failure.RetryAfter = rec.job.partner.breaker.cooldown
+		failure.Postponed = true
+		return failure
+	}
+	// успешные опросы счетчик сбрасывают, поэтому считаются только неудачи подряд
+	failed := rec.job.attempts + 1
+	if a.retryPolicy.exhausted(failed, rec.job.createdAt, time.Now()) {
+		logger.Log.Debugf("give up polling order %s after %d failed polls", rec.job.orderID, failed)
+		failure.GiveUp = true
+		return failure
+	}
+	failure.RetryAfter = a.retryPolicy.delay(failed)
+	return failure
+}
+
 func (a *AccrualAgent) startAccrualProcess(ctx context.Context) {
 	for {
 		select {
 		case <-a.accrualTicker.C:
-			a.getAccrualInfoTick()
+			a.getAccrualInfoTick(ctx)
 		case <-ctx.Done():
+			a.accrualTicker.Stop()
 			logger.Log.Debug("accrual process ended")
 			return
 		}
@@ -0,0 +1,215 @@
+package accrualagent
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	accrualmock "github.com/ShvetsovYura/oygophermart/internal/accrual_mock"
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/options"
+	"github.com/stretchr/testify/assert"
+)
+
+type saverStub struct {
+	mu      sync.Mutex
+	jobs    []models.AccrualJobModel
+	updated []models.AccrualResult
+}
+
+func (s *saverStub) ClaimAccrualJobs(ctx context.Context, limit int, lease time.Duration) ([]models.AccrualJobModel, error) {
+	return s.jobs, nil
+}
+
+func (s *saverStub) FailAccrualJobs(ctx context.Context, failures ...models.AccrualJobFailure) error {
+	return nil
+}
+
+func (s *saverStub) UpdateOrdersStatus(ctx context.Context, records ...models.AccrualResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updated = append(s.updated, records...)
+	return nil
+}
+
+func (s *saverStub) AssignOrdersPartner(ctx context.Context, partner string, orderIDs ...string) error {
+	return nil
+}
+
+// clientStub отвечает по сценарию: очередной шаг на каждый запрос заказа,
+// последний шаг повторяется; шаг со статусом ERROR - недоступность партнера
+type clientStub struct {
+	mu      sync.Mutex
+	steps   []string
+	accrual float64
+	calls   int
+}
+
+func (c *clientStub) GetOrder(ctx context.Context, orderID string) (*models.AccrualResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	step := c.steps[min(c.calls, len(c.steps)-1)]
+	c.calls++
+	if step == accrualmock.StatusError {
+		return nil, ErrAccrualUnavailable
+	}
+	res := &models.AccrualResult{OrderID: orderID, Status: step}
+	if step == accrualmock.StatusProcessed {
+		res.Accrual = &c.accrual
+	}
+	return res, nil
+}
+
+// stubClients возвращает фабрику, которая отдает партнерам заранее созданные клиенты
+func stubClients(clients map[string]*clientStub) ClientFactory {
+	return func(opts options.PartnerOptions) (AccrualClient, error) {
+		c, ok := clients[opts.Name]
+		if !ok {
+			c = &clientStub{steps: []string{accrualmock.StatusProcessed}}
+			clients[opts.Name] = c
+		}
+		return c, nil
+	}
+}
+
+func TestAgentFlushesOnShutdown(t *testing.T) {
+	saver := &saverStub{}
+	a, err := NewAccrualAgent([]options.PartnerOptions{{Name: options.DefaultPartner}}, stubClients(map[string]*clientStub{}), saver, 60, RetryPolicy{})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		a.Start(ctx)
+		close(done)
+	}()
+
+	a.taskResultCh <- TaskResult{data: &models.AccrualResult{OrderID: "12345678903", Status: "PROCESSED"}}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent did not stop")
+	}
+	assert.Len(t, saver.updated, 1)
+}
+
+func TestAgentAgainstMock(t *testing.T) {
+	mock, err := accrualmock.NewServer(accrualmock.Options{Prefixes: map[string]string{"9": "unregistered"}})
+	assert.NoError(t, err)
+	srv := httptest.NewServer(mock.Handler())
+	defer srv.Close()
+
+	saver := &saverStub{jobs: []models.AccrualJobModel{
+		{OrderID: "12345678903", Partner: options.DefaultPartner, Attempts: 1, CreatedAt: time.Now()},
+		{OrderID: "9278923470", Partner: options.DefaultPartner, Attempts: 1, CreatedAt: time.Now()},
+	}}
+	a, err := NewAccrualAgent([]options.PartnerOptions{{Name: options.DefaultPartner, URL: srv.URL}}, nil, saver, 60, RetryPolicy{Base: time.Second})
+	assert.NoError(t, err)
+
+	var statuses []string
+	for i := 0; i < 3; i++ {
+		a.getAccrualInfoTick(context.Background())
+		for range saver.jobs {
+			rec := <-a.taskResultCh
+			switch rec.job.orderID {
+			case "12345678903":
+				assert.NoError(t, rec.err)
+				statuses = append(statuses, rec.data.Status)
+			case "9278923470":
+				assert.ErrorIs(t, rec.err, ErrOrderNotRegistered)
+				assert.False(t, a.jobFailure(rec).GiveUp)
+			}
+		}
+	}
+	assert.Equal(t, []string{"REGISTERED", "PROCESSING", "PROCESSED"}, statuses)
+	assert.Equal(t, 6, mock.Requests())
+}
+
+// queueStub ведет счетчик неудач задания так же, как очередь в БД:
+// неудачный опрос увеличивает его, успешный сбрасывает
+type queueStub struct {
+	saverStub
+	job models.AccrualJobModel
+}
+
+func (s *queueStub) ClaimAccrualJobs(ctx context.Context, limit int, lease time.Duration) ([]models.AccrualJobModel, error) {
+	return []models.AccrualJobModel{s.job}, nil
+}
+
+func (s *queueStub) FailAccrualJobs(ctx context.Context, failures ...models.AccrualJobFailure) error {
+	for _, f := range failures {
+		if !f.Postponed {
+			s.job.Attempts++
+		}
+	}
+	return nil
+}
+
+func (s *queueStub) UpdateOrdersStatus(ctx context.Context, records ...models.AccrualResult) error {
+	s.job.Attempts = 0
+	return s.saverStub.UpdateOrdersStatus(ctx, records...)
+}
+
+func TestAgentLongProcessingThenTransientError(t *testing.T) {
+	steps := make([]string, 0, 12)
+	for i := 0; i < 10; i++ {
+		steps = append(steps, accrualmock.StatusProcessing)
+	}
+	steps = append(steps, accrualmock.StatusError, accrualmock.StatusProcessed)
+	client := &clientStub{steps: steps, accrual: 100}
+
+	saver := &queueStub{job: models.AccrualJobModel{
+		OrderID: "12345678903", Partner: options.DefaultPartner, CreatedAt: time.Now().Add(-time.Hour),
+	}}
+	a, err := NewAccrualAgent([]options.PartnerOptions{{Name: options.DefaultPartner}},
+		stubClients(map[string]*clientStub{options.DefaultPartner: client}), saver, 60,
+		RetryPolicy{Base: time.Second, MaxAttempts: 3, MaxAge: 24 * time.Hour})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 0; i < len(steps); i++ {
+		a.getAccrualInfoTick(ctx)
+		rec := <-a.taskResultCh
+		if rec.err != nil {
+			failure := a.jobFailure(rec)
+			assert.False(t, failure.GiveUp, "poll %d", i)
+			assert.NoError(t, saver.FailAccrualJobs(ctx, failure))
+			continue
+		}
+		assert.NoError(t, saver.UpdateOrdersStatus(ctx, *rec.data))
+	}
+	assert.Equal(t, len(steps), client.calls)
+	assert.Equal(t, 0, saver.job.Attempts)
+	assert.Equal(t, "PROCESSED", saver.updated[len(saver.updated)-1].Status)
+}
+
+func TestAgentPollsPartnerClient(t *testing.T) {
+	clients := map[string]*clientStub{
+		"acme":                 {steps: []string{accrualmock.StatusProcessed}, accrual: 10},
+		options.DefaultPartner: {steps: []string{accrualmock.StatusProcessing}},
+	}
+	saver := &saverStub{jobs: []models.AccrualJobModel{
+		{OrderID: "AB12345H", CreatedAt: time.Now()},
+		{OrderID: "12345678903", CreatedAt: time.Now()},
+	}}
+	a, err := NewAccrualAgent([]options.PartnerOptions{
+		{Name: "acme", Prefixes: []string{"AB"}},
+		{Name: options.DefaultPartner},
+	}, stubClients(clients), saver, 60, RetryPolicy{})
+	assert.NoError(t, err)
+
+	a.getAccrualInfoTick(context.Background())
+	statuses := make(map[string]string)
+	for range saver.jobs {
+		rec := <-a.taskResultCh
+		assert.NoError(t, rec.err)
+		statuses[rec.data.OrderID] = rec.data.Status
+	}
+	assert.Equal(t, map[string]string{"AB12345H": "PROCESSED", "12345678903": "PROCESSING"}, statuses)
+	assert.Equal(t, 1, clients["acme"].calls)
+	assert.Equal(t, 1, clients[options.DefaultPartner].calls)
+}
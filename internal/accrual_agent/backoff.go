@@ -0,0 +1,58 @@
+package accrualagent
+
+import (
+	"math/rand"
+	"time"
+)
+
+const maxDoublings = 32
+
+// RetryPolicy задает задержки между неудачными опросами заказа и момент,
+// после которого опрос прекращается и заказ получает статус UNKNOWN.
+// Poll - пауза перед повторным опросом заказа, который партнер еще обрабатывает.
+type RetryPolicy struct {
+	Base        time.Duration
+	Max         time.Duration
+	MaxAttempts int
+	MaxAge      time.Duration
+	Poll        time.Duration
+}
+
+// delay возвращает задержку перед следующей попыткой: Base * 2^(attempts-1),
+// но не больше Max, со случайным разбросом в пределах второй половины интервала
+func (p RetryPolicy) delay(attempts int) time.Duration {
+	d := p.Base
+	for i := 1; i < attempts && i < maxDoublings && (p.Max <= 0 || d < p.Max); i++ {
+		d *= 2
+	}
+	if p.Max > 0 && d > p.Max {
+		d = p.Max
+	}
+	return jitter(d)
+}
+
+// pollDelay возвращает паузу перед следующим опросом заказа с неитоговым статусом
+func (p RetryPolicy) pollDelay() time.Duration {
+	return jitter(p.Poll)
+}
+
+// jitter возвращает случайную задержку из второй половины d,
+// чтобы опросы заказов, взятых одной пачкой, расходились во времени
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// exhausted сообщает, что попытки или допустимый возраст заказа исчерпаны
+func (p RetryPolicy) exhausted(attempts int, createdAt time.Time, now time.Time) bool {
+	if p.MaxAttempts > 0 && attempts >= p.MaxAttempts {
+		return true
+	}
+	if p.MaxAge > 0 && now.Sub(createdAt) > p.MaxAge {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package accrualagent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Base: time.Second, Max: 30 * time.Second}
+
+	tests := []struct {
+		attempts int
+		min      time.Duration
+		max      time.Duration
+	}{
+		{attempts: 1, min: 500 * time.Millisecond, max: time.Second},
+		{attempts: 3, min: 2 * time.Second, max: 4 * time.Second},
+		{attempts: 5, min: 8 * time.Second, max: 16 * time.Second},
+		{attempts: 40, min: 15 * time.Second, max: 30 * time.Second},
+	}
+	for _, test := range tests {
+		for i := 0; i < 20; i++ {
+			d := p.delay(test.attempts)
+			assert.GreaterOrEqual(t, d, test.min, test.attempts)
+			assert.LessOrEqual(t, d, test.max, test.attempts)
+		}
+	}
+}
+
+func TestRetryPolicyExhausted(t *testing.T) {
+	now := time.Now()
+	p := RetryPolicy{MaxAttempts: 5, MaxAge: time.Hour}
+
+	assert.False(t, p.exhausted(4, now.Add(-time.Minute), now))
+	assert.True(t, p.exhausted(5, now.Add(-time.Minute), now))
+	assert.True(t, p.exhausted(1, now.Add(-2*time.Hour), now))
+	assert.False(t, RetryPolicy{}.exhausted(1000, now.Add(-1000*time.Hour), now))
+}
+
+func TestRetryPolicyPollDelay(t *testing.T) {
+	p := RetryPolicy{Poll: 10 * time.Second}
+	for i := 0; i < 20; i++ {
+		d := p.pollDelay()
+		assert.GreaterOrEqual(t, d, 5*time.Second)
+		assert.LessOrEqual(t, d, 10*time.Second)
+	}
+	assert.Zero(t, RetryPolicy{}.pollDelay())
+}
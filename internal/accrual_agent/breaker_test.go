@@ -0,0 +1,41 @@
+package accrualagent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := newCircuitBreaker(2, 50*time.Millisecond)
+
+	ok, _ := b.Allow()
+	assert.True(t, ok)
+	b.Failure()
+	assert.Equal(t, breakerClosed, b.State().State)
+	b.Failure()
+	assert.Equal(t, breakerOpen, b.State().State)
+
+	ok, wait := b.Allow()
+	assert.False(t, ok)
+	assert.Greater(t, wait, time.Duration(0))
+
+	time.Sleep(60 * time.Millisecond)
+	// после паузы пропускается только один пробный запрос
+	ok, _ = b.Allow()
+	assert.True(t, ok)
+	assert.Equal(t, breakerHalfOpen, b.State().State)
+	ok, _ = b.Allow()
+	assert.False(t, ok)
+
+	b.Failure()
+	assert.Equal(t, breakerOpen, b.State().State)
+
+	time.Sleep(60 * time.Millisecond)
+	ok, _ = b.Allow()
+	assert.True(t, ok)
+	b.Success()
+	assert.Equal(t, breakerClosed, b.State().State)
+	assert.Equal(t, 0, b.State().Failures)
+}
@@ -0,0 +1,161 @@
+package accrualagent
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/options"
+	"github.com/dghubble/sling"
+)
+
+const (
+	defaultRequestTimeout = 10 * time.Second
+	defaultMaxBodySize    = 1 << 20
+	defaultRetryAfter     = 5
+)
+
+var ErrAccrualUnavailable = errors.New("accrual service unavailable")
+var ErrAccrualResponseTooLarge = errors.New("accrual response is too large")
+
+// AccrualClient запрашивает у системы начислений статус заказа.
+// Ошибки ErrAccrualUnavailable означают сбой самой системы (5xx, сеть, таймаут).
+type AccrualClient interface {
+	GetOrder(ctx context.Context, orderID string) (*models.AccrualResult, error)
+}
+
+// ClientFactory создает клиента системы начислений партнера
+type ClientFactory func(opts options.PartnerOptions) (AccrualClient, error)
+
+type httpAccrualClient struct {
+	base        *sling.Sling
+	client      *http.Client
+	timeout     time.Duration
+	maxBodySize int64
+}
+
+// NewHTTPAccrualClient создает клиента партнера с собственным пулом соединений
+func NewHTTPAccrualClient(opts options.PartnerOptions) (AccrualClient, error) {
+	tlsConfig, err := partnerTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.MaxIdleConnsPerHost = 16
+
+	timeout := defaultRequestTimeout
+	if opts.Timeout != "" {
+		timeout, err = time.ParseDuration(opts.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, options.ErrPartnersNotValid
+		}
+	}
+	maxBodySize := opts.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMaxBodySize
+	}
+
+	client := &http.Client{Transport: transport}
+	base := sling.New().Client(client).Base(opts.URL).Set("User-Agent", "OyGopherMart client")
+	for k, v := range opts.Headers {
+		base = base.Set(k, v)
+	}
+	if opts.Token != "" {
+		base = base.Set("Authorization", "Bearer "+opts.Token)
+	}
+	if opts.User != "" {
+		base = base.SetBasicAuth(opts.User, opts.Password)
+	}
+	return &httpAccrualClient{
+		base:        base,
+		client:      client,
+		timeout:     timeout,
+		maxBodySize: maxBodySize,
+	}, nil
+}
+
+func partnerTLSConfig(opts options.PartnerOptions) (*tls.Config, error) {
+	if opts.CACert == "" && opts.ClientCert == "" && !opts.InsecureSkipVerify {
+		return nil, nil
+	}
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+	if opts.CACert != "" {
+		pem, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: no certificates in %s", options.ErrPartnersNotValid, opts.CACert)
+		}
+		cfg.RootCAs = pool
+	}
+	if opts.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+func (c *httpAccrualClient) GetOrder(ctx context.Context, orderID string) (*models.AccrualResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	r, err := c.base.New().Get("/api/orders/" + orderID).Request()
+	if err != nil {
+		return nil, err
+	}
+	r = r.WithContext(ctx)
+
+	resp, err := c.client.Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccrualUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		body, err := io.ReadAll(io.LimitReader(resp.Body, c.maxBodySize+1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrAccrualUnavailable, err)
+		}
+		if int64(len(body)) > c.maxBodySize {
+			return nil, ErrAccrualResponseTooLarge
+		}
+		var result models.AccrualResult
+		err = json.Unmarshal(body, &result)
+		if err != nil {
+			return nil, err
+		}
+		return &result, nil
+	case http.StatusNoContent:
+		return nil, ErrOrderNotRegistered
+	case http.StatusTooManyRequests:
+		retryafter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+		if err != nil {
+			retryafter = defaultRetryAfter
+		}
+		return nil, NewTooManyRequestsError(ErrTooManyIntegrationRequests, retryafter)
+	default:
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return nil, fmt.Errorf("%w: status %d", ErrAccrualUnavailable, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("unexpected accrual response status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,106 @@
+package accrualagent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShvetsovYura/oygophermart/internal/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPAccrualClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-Partner-Key"))
+		switch r.URL.Path {
+		case "/api/orders/12345678903":
+			w.Write([]byte(`{"order":"12345678903","status":"PROCESSED","accrual":500}`))
+		case "/api/orders/2377225624":
+			w.WriteHeader(http.StatusNoContent)
+		case "/api/orders/9278923470":
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/api/orders/346436439":
+			w.Write([]byte(`{"order":"346436439","status":"` + strings.Repeat("X", 100) + `"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewHTTPAccrualClient(options.PartnerOptions{
+		Name:        options.DefaultPartner,
+		URL:         srv.URL,
+		Headers:     map[string]string{"X-Partner-Key": "secret"},
+		MaxBodySize: 80,
+	})
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	result, err := client.GetOrder(ctx, "12345678903")
+	assert.NoError(t, err)
+	assert.Equal(t, "PROCESSED", result.Status)
+	assert.Equal(t, 500.0, *result.Accrual)
+
+	_, err = client.GetOrder(ctx, "2377225624")
+	assert.ErrorIs(t, err, ErrOrderNotRegistered)
+
+	_, err = client.GetOrder(ctx, "9278923470")
+	var target *TooManyRequestsError
+	assert.True(t, errors.As(err, &target))
+	assert.Equal(t, 60, target.RetryAfter)
+
+	_, err = client.GetOrder(ctx, "346436439")
+	assert.ErrorIs(t, err, ErrAccrualResponseTooLarge)
+
+	_, err = client.GetOrder(ctx, "4561261212345467")
+	assert.ErrorIs(t, err, ErrAccrualUnavailable)
+}
+
+func TestAgentOpensCircuit(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	a, err := NewAccrualAgent([]options.PartnerOptions{{Name: options.DefaultPartner, URL: srv.URL}}, nil, nil, 1, RetryPolicy{})
+	assert.NoError(t, err)
+	p := a.partners.def
+
+	for i := 0; i < defaultBreakerThreshold; i++ {
+		_, err = a.getAccrualStatusRequest(context.Background(), p, "12345678903")
+		assert.ErrorIs(t, err, ErrAccrualUnavailable)
+	}
+	_, err = a.getAccrualStatusRequest(context.Background(), p, "12345678903")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, defaultBreakerThreshold, calls)
+	assert.Equal(t, breakerOpen, a.Health().Partners[0].State)
+}
+
+func TestAgentCancelledRequestKeepsCircuit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	a, err := NewAccrualAgent([]options.PartnerOptions{{Name: options.DefaultPartner, URL: srv.URL}}, nil, nil, 1, RetryPolicy{})
+	assert.NoError(t, err)
+	p := a.partners.def
+
+	for i := 0; i < defaultBreakerThreshold; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err = a.getAccrualStatusRequest(ctx, p, "12345678903")
+		cancel()
+		assert.ErrorIs(t, err, ErrAccrualUnavailable)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+	state := p.breaker.State()
+	assert.Equal(t, breakerClosed, state.State)
+	assert.Equal(t, 0, state.Failures)
+}
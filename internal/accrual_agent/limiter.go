@@ -0,0 +1,134 @@
+package accrualagent
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxRate = 50
+	minRate        = 0.1
+	// прибавка к частоте после каждого успешного запроса
+	rateIncrease = 0.1
+)
+
+// rateLimiter - token bucket, общий для всех воркеров одного партнера.
+// 429 останавливает выдачу токенов до истечения Retry-After и вдвое снижает частоту,
+// успешные ответы постепенно возвращают ее к максимальной.
+type rateLimiter struct {
+	mu           sync.Mutex
+	rate         float64
+	maxRate      float64
+	burst        float64
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+	throttled    int64
+}
+
+// limiterState - текущее состояние ограничителя партнера для отладки
+type limiterState struct {
+	Rate         float64
+	MaxRate      float64
+	Tokens       float64
+	BlockedUntil *time.Time
+	Throttled    int64
+}
+
+func newRateLimiter(maxRate float64) *rateLimiter {
+	if maxRate <= 0 {
+		maxRate = defaultMaxRate
+	}
+	burst := math.Max(1, math.Floor(maxRate))
+	return &rateLimiter{
+		rate:    maxRate,
+		maxRate: maxRate,
+		burst:   burst,
+		tokens:  burst,
+		last:    time.Now(),
+	}
+}
+
+func (l *rateLimiter) refill(now time.Time) {
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+}
+
+// reserve забирает токен или возвращает, сколько нужно подождать до следующей попытки
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Before(l.blockedUntil) {
+		return l.blockedUntil.Sub(now)
+	}
+	l.refill(now)
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// Wait блокирует до получения токена. Если ожидание не укладывается в срок ctx,
+// сразу возвращает TooManyRequestsError с оставшимся временем ожидания.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	for {
+		now := time.Now()
+		wait := l.reserve(now)
+		if wait == 0 {
+			return nil
+		}
+		if deadline, ok := ctx.Deadline(); ok && now.Add(wait).After(deadline) {
+			return NewTooManyRequestsError(ErrTooManyIntegrationRequests, int(math.Ceil(wait.Seconds())))
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Throttle останавливает все запросы к партнеру на retryAfter и снижает частоту
+func (l *rateLimiter) Throttle(retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if until := now.Add(retryAfter); until.After(l.blockedUntil) {
+		l.blockedUntil = until
+	}
+	l.rate = math.Max(minRate, l.rate/2)
+	l.tokens = 0
+	l.last = now
+	l.throttled++
+}
+
+// Success увеличивает частоту после успешного запроса
+func (l *rateLimiter) Success() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.rate = math.Min(l.maxRate, l.rate+rateIncrease)
+}
+
+func (l *rateLimiter) State() limiterState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.refill(now)
+	state := limiterState{
+		Rate:      l.rate,
+		MaxRate:   l.maxRate,
+		Tokens:    l.tokens,
+		Throttled: l.throttled,
+	}
+	if now.Before(l.blockedUntil) {
+		until := l.blockedUntil
+		state.BlockedUntil = &until
+	}
+	return state
+}
@@ -0,0 +1,44 @@
+package accrualagent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterThrottle(t *testing.T) {
+	l := newRateLimiter(10)
+	assert.Equal(t, 10.0, l.State().Rate)
+
+	l.Throttle(time.Minute)
+	state := l.State()
+	assert.Equal(t, 5.0, state.Rate)
+	assert.Equal(t, int64(1), state.Throttled)
+	assert.NotNil(t, state.BlockedUntil)
+
+	// ожидание не укладывается в срок - сразу возвращается время до разблокировки
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := l.Wait(ctx)
+	var target *TooManyRequestsError
+	assert.True(t, errors.As(err, &target))
+	assert.Equal(t, 60, target.RetryAfter)
+
+	l.Success()
+	assert.InDelta(t, 5.1, l.State().Rate, 0.001)
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := newRateLimiter(2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, l.Wait(ctx))
+	}
+	// два токена есть сразу, третий появляется через 0.5с
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
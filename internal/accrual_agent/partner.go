@@ -0,0 +1,123 @@
+package accrualagent
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/options"
+)
+
+type partner struct {
+	opts    options.PartnerOptions
+	client  AccrualClient
+	limiter *rateLimiter
+	breaker *circuitBreaker
+}
+
+// rps партнера задает максимальную частоту запросов
+func newPartner(opts options.PartnerOptions, newClient ClientFactory) (*partner, error) {
+	client, err := newClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &partner{
+		opts:    opts,
+		client:  client,
+		limiter: newRateLimiter(opts.RPS),
+		breaker: newCircuitBreaker(defaultBreakerThreshold, defaultBreakerCooldown),
+	}, nil
+}
+
+type partnerRoute struct {
+	prefix  string
+	partner *partner
+}
+
+type partnerRouter struct {
+	partners map[string]*partner
+	routes   []partnerRoute
+	def      *partner
+}
+
+// newClient == nil - клиенты ходят к партнерам по HTTP
+func newPartnerRouter(partners []options.PartnerOptions, newClient ClientFactory) (*partnerRouter, error) {
+	if newClient == nil {
+		newClient = NewHTTPAccrualClient
+	}
+	r := &partnerRouter{partners: make(map[string]*partner, len(partners))}
+	for _, opts := range partners {
+		p, err := newPartner(opts, newClient)
+		if err != nil {
+			return nil, err
+		}
+		r.partners[opts.Name] = p
+		for _, prefix := range opts.Prefixes {
+			r.routes = append(r.routes, partnerRoute{prefix: prefix, partner: p})
+		}
+		if opts.Name == options.DefaultPartner {
+			r.def = p
+		}
+	}
+	sort.SliceStable(r.routes, func(i, j int) bool {
+		return len(r.routes[i].prefix) > len(r.routes[j].prefix)
+	})
+	return r, nil
+}
+
+// limiterStates возвращает состояние ограничителей запросов по партнерам
+func (r *partnerRouter) limiterStates() []models.LimiterStateResp {
+	states := make([]models.LimiterStateResp, 0, len(r.partners))
+	for name, p := range r.partners {
+		st := p.limiter.State()
+		states = append(states, models.LimiterStateResp{
+			Partner:      name,
+			Rate:         st.Rate,
+			MaxRate:      st.MaxRate,
+			Tokens:       st.Tokens,
+			BlockedUntil: st.BlockedUntil,
+			Throttled:    st.Throttled,
+		})
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Partner < states[j].Partner
+	})
+	return states
+}
+
+// breakerStates возвращает состояние цепей запросов по партнерам
+func (r *partnerRouter) breakerStates() []models.BreakerStateResp {
+	states := make([]models.BreakerStateResp, 0, len(r.partners))
+	for name, p := range r.partners {
+		st := p.breaker.State()
+		states = append(states, models.BreakerStateResp{
+			Partner:  name,
+			State:    st.State,
+			Failures: st.Failures,
+			OpenedAt: st.OpenedAt,
+			RetryAt:  st.RetryAt,
+		})
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Partner < states[j].Partner
+	})
+	return states
+}
+
+func (r *partnerRouter) route(orderID string) *partner {
+	for _, route := range r.routes {
+		if strings.HasPrefix(orderID, route.prefix) {
+			return route.partner
+		}
+	}
+	return r.def
+}
+
+// byName возвращает сохраненного за заказом партнера;
+// если партнер пропал из конфигурации, заказ маршрутизируется заново
+func (r *partnerRouter) byName(name string, orderID string) *partner {
+	if p, ok := r.partners[name]; ok {
+		return p
+	}
+	return r.route(orderID)
+}
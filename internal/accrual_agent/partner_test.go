@@ -0,0 +1,33 @@
+package accrualagent
+
+import (
+	"testing"
+
+	"github.com/ShvetsovYura/oygophermart/internal/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartnerRouter(t *testing.T) {
+	r, err := newPartnerRouter([]options.PartnerOptions{
+		{Name: "acme", URL: "http://acme", Prefixes: []string{"AB", "12"}},
+		{Name: "acme-gold", URL: "http://gold", Prefixes: []string{"ABG"}},
+		{Name: options.DefaultPartner, URL: "http://default"},
+	}, nil)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		orderID  string
+		expected string
+	}{
+		{orderID: "AB12345H", expected: "acme"},
+		{orderID: "ABG1234", expected: "acme-gold"},
+		{orderID: "12345678903", expected: "acme"},
+		{orderID: "34544556646468", expected: options.DefaultPartner},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, r.route(test.orderID).opts.Name, test.orderID)
+	}
+
+	assert.Equal(t, "acme-gold", r.byName("acme-gold", "12345678903").opts.Name)
+	assert.Equal(t, "acme", r.byName("removed", "12345678903").opts.Name)
+}
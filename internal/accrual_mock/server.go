@@ -0,0 +1,245 @@
+// Package accrualmock - заглушка системы начислений для локального запуска и тестов.
+// Каждый запрос статуса продвигает заказ на шаг по его сценарию.
+package accrualmock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	StatusRegistered = "REGISTERED"
+	StatusProcessing = "PROCESSING"
+	StatusProcessed  = "PROCESSED"
+	StatusInvalid    = "INVALID"
+	// заказ не зарегистрирован - ответ 204
+	StatusUnregistered = "UNREGISTERED"
+	// сбой системы - ответ 500
+	StatusError = "ERROR"
+)
+
+// Scenario - последовательность статусов заказа; последний повторяется
+type Scenario struct {
+	Steps   []string `json:"steps"`
+	Accrual float64  `json:"accrual,omitempty"`
+}
+
+var DefaultScenarios = map[string]Scenario{
+	"processed":    {Steps: []string{StatusRegistered, StatusProcessing, StatusProcessed}, Accrual: 500},
+	"invalid":      {Steps: []string{StatusRegistered, StatusInvalid}},
+	"unregistered": {Steps: []string{StatusUnregistered}},
+	"error":        {Steps: []string{StatusError}},
+}
+
+var ErrUnknownScenario = errors.New("unknown scenario")
+var ErrScenarioNotValid = errors.New("scenario is not valid")
+
+type Options struct {
+	// сценарии по имени, дополняют DefaultScenarios
+	Scenarios map[string]Scenario `json:"scenarios,omitempty"`
+	// имя сценария по префиксу номера заказа, выбирается самый длинный префикс
+	Prefixes map[string]string `json:"prefixes,omitempty"`
+	// сценарий для остальных заказов
+	Default string `json:"default,omitempty"`
+
+	// задержка ответа и ее случайный разброс, в файле настроек не задаются
+	Latency time.Duration `json:"-"`
+	Jitter  time.Duration `json:"-"`
+	// доля запросов, на которые отвечаем 500
+	ErrorRate float64 `json:"error_rate,omitempty"`
+	// запросов в секунду, сверх которых отвечаем 429; 0 - без ограничения
+	RPS        int `json:"rps,omitempty"`
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+type Server struct {
+	opts      Options
+	scenarios map[string]Scenario
+	prefixes  []string
+
+	mu          sync.Mutex
+	orders      map[string]Scenario
+	steps       map[string]int
+	window      time.Time
+	windowCount int
+	rnd         *rand.Rand
+	request     int
+}
+
+// NewServer проверяет, что сценарии по умолчанию и по префиксам существуют
+// и в каждом сценарии есть известные шаги
+func NewServer(opts Options) (*Server, error) {
+	s := &Server{
+		opts:      opts,
+		scenarios: make(map[string]Scenario, len(DefaultScenarios)+len(opts.Scenarios)),
+		orders:    make(map[string]Scenario),
+		steps:     make(map[string]int),
+		rnd:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for name, sc := range DefaultScenarios {
+		s.scenarios[name] = sc
+	}
+	for name, sc := range opts.Scenarios {
+		s.scenarios[name] = sc
+	}
+	if s.opts.Default == "" {
+		s.opts.Default = "processed"
+	}
+	if s.opts.RetryAfter <= 0 {
+		s.opts.RetryAfter = 1
+	}
+	for name, sc := range s.scenarios {
+		if err := validateScenario(sc); err != nil {
+			return nil, fmt.Errorf("%w: %s", err, name)
+		}
+	}
+	if _, ok := s.scenarios[s.opts.Default]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownScenario, s.opts.Default)
+	}
+	for prefix, name := range opts.Prefixes {
+		if _, ok := s.scenarios[name]; !ok {
+			return nil, fmt.Errorf("%w: %s for prefix %s", ErrUnknownScenario, name, prefix)
+		}
+	}
+	for prefix := range opts.Prefixes {
+		s.prefixes = append(s.prefixes, prefix)
+	}
+	sort.Slice(s.prefixes, func(i, j int) bool {
+		return len(s.prefixes[i]) > len(s.prefixes[j])
+	})
+	return s, nil
+}
+
+func validateScenario(sc Scenario) error {
+	if len(sc.Steps) == 0 {
+		return ErrScenarioNotValid
+	}
+	for _, step := range sc.Steps {
+		switch step {
+		case StatusRegistered, StatusProcessing, StatusProcessed, StatusInvalid, StatusUnregistered, StatusError:
+		default:
+			return ErrScenarioNotValid
+		}
+	}
+	return nil
+}
+
+func (s *Server) Handler() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/api/orders/{number}", s.getOrder)
+	return r
+}
+
+// SetScenario задает сценарий заказа и сбрасывает его прогресс
+func (s *Server) SetScenario(orderID string, sc Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orders[orderID] = sc
+	delete(s.steps, orderID)
+}
+
+// Requests возвращает число обработанных запросов
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.request
+}
+
+func (s *Server) scenario(orderID string) Scenario {
+	if sc, ok := s.orders[orderID]; ok {
+		return sc
+	}
+	name := s.opts.Default
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(orderID, prefix) {
+			name = s.opts.Prefixes[prefix]
+			break
+		}
+	}
+	return s.scenarios[name]
+}
+
+// next возвращает очередной статус заказа или код ответа при внедренном сбое
+func (s *Server) next(orderID string, now time.Time) (int, *models.AccrualResult, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.request++
+
+	latency := s.opts.Latency
+	if s.opts.Jitter > 0 {
+		latency += time.Duration(s.rnd.Int63n(int64(s.opts.Jitter)))
+	}
+	if s.opts.RPS > 0 {
+		if now.Sub(s.window) >= time.Second {
+			s.window = now
+			s.windowCount = 0
+		}
+		s.windowCount++
+		if s.windowCount > s.opts.RPS {
+			return http.StatusTooManyRequests, nil, latency
+		}
+	}
+	if s.opts.ErrorRate > 0 && s.rnd.Float64() < s.opts.ErrorRate {
+		return http.StatusInternalServerError, nil, latency
+	}
+
+	sc := s.scenario(orderID)
+	if len(sc.Steps) == 0 {
+		return http.StatusNoContent, nil, latency
+	}
+	step := s.steps[orderID]
+	if step < len(sc.Steps)-1 {
+		s.steps[orderID] = step + 1
+	}
+	status := sc.Steps[step]
+	switch status {
+	case StatusUnregistered:
+		return http.StatusNoContent, nil, latency
+	case StatusError:
+		return http.StatusInternalServerError, nil, latency
+	}
+	result := &models.AccrualResult{OrderID: orderID, Status: status}
+	if status == StatusProcessed {
+		accrual := sc.Accrual
+		result.Accrual = &accrual
+	}
+	return http.StatusOK, result, latency
+}
+
+func (s *Server) getOrder(w http.ResponseWriter, r *http.Request) {
+	code, result, latency := s.next(chi.URLParam(r, "number"), time.Now())
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	switch code {
+	case http.StatusOK:
+		resp, err := json.Marshal(result)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.Write(resp)
+	case http.StatusTooManyRequests:
+		w.Header().Set("Retry-After", strconv.Itoa(s.opts.RetryAfter))
+		w.WriteHeader(code)
+		w.Write([]byte("No more than " + strconv.Itoa(s.opts.RPS) + " requests per second allowed"))
+	default:
+		w.WriteHeader(code)
+	}
+}
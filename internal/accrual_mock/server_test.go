@@ -0,0 +1,93 @@
+package accrualmock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func getStatus(t *testing.T, url string) (int, *models.AccrualResult) {
+	resp, err := http.Get(url)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+	var result models.AccrualResult
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return resp.StatusCode, &result
+}
+
+func TestServerScenarios(t *testing.T) {
+	mock, err := NewServer(Options{Prefixes: map[string]string{"9": "invalid", "5": "unregistered"}})
+	assert.NoError(t, err)
+	srv := httptest.NewServer(mock.Handler())
+	defer srv.Close()
+
+	expected := []string{StatusRegistered, StatusProcessing, StatusProcessed, StatusProcessed}
+	for _, status := range expected {
+		code, result := getStatus(t, srv.URL+"/api/orders/12345678903")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, status, result.Status)
+	}
+
+	_, result := getStatus(t, srv.URL+"/api/orders/9278923470")
+	assert.Equal(t, StatusRegistered, result.Status)
+	_, result = getStatus(t, srv.URL+"/api/orders/9278923470")
+	assert.Equal(t, StatusInvalid, result.Status)
+
+	code, _ := getStatus(t, srv.URL+"/api/orders/5062821234567892")
+	assert.Equal(t, http.StatusNoContent, code)
+
+	mock.SetScenario("2377225624", Scenario{Steps: []string{StatusError, StatusProcessed}, Accrual: 42})
+	code, _ = getStatus(t, srv.URL+"/api/orders/2377225624")
+	assert.Equal(t, http.StatusInternalServerError, code)
+	_, result = getStatus(t, srv.URL+"/api/orders/2377225624")
+	assert.Equal(t, 42.0, *result.Accrual)
+}
+
+func TestServerRateLimit(t *testing.T) {
+	mock, err := NewServer(Options{RPS: 2, RetryAfter: 7})
+	assert.NoError(t, err)
+	srv := httptest.NewServer(mock.Handler())
+	defer srv.Close()
+
+	for i := 0; i < 2; i++ {
+		code, _ := getStatus(t, srv.URL+"/api/orders/12345678903")
+		assert.Equal(t, http.StatusOK, code)
+	}
+	resp, err := http.Get(srv.URL + "/api/orders/12345678903")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "7", resp.Header.Get("Retry-After"))
+}
+
+func TestNewServerValidatesScenarios(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		err  error
+	}{
+		{"defaults", Options{}, nil},
+		{"custom scenario", Options{Scenarios: map[string]Scenario{"slow": {Steps: []string{StatusProcessing}}}, Default: "slow"}, nil},
+		{"unknown default", Options{Default: "procesed"}, ErrUnknownScenario},
+		{"unknown prefix scenario", Options{Prefixes: map[string]string{"9": "invlaid"}}, ErrUnknownScenario},
+		{"empty steps", Options{Scenarios: map[string]Scenario{"empty": {}}}, ErrScenarioNotValid},
+		{"unknown step", Options{Scenarios: map[string]Scenario{"bad": {Steps: []string{"DONE"}}}}, ErrScenarioNotValid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewServer(tt.opts)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
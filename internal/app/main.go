@@ -2,36 +2,68 @@ package app
 
 import (
 	"context"
-	"fmt"
+	"time"
 
 	accrualagent "github.com/ShvetsovYura/oygophermart/internal/accrual_agent"
+	"github.com/ShvetsovYura/oygophermart/internal/logger"
 	"github.com/ShvetsovYura/oygophermart/internal/options"
 	"github.com/ShvetsovYura/oygophermart/internal/store"
 	"github.com/ShvetsovYura/oygophermart/internal/webserver"
 	"github.com/ShvetsovYura/oygophermart/migrations"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/sync/errgroup"
 )
 
+const shutdownTimeout = 15 * time.Second
+
+// Run запускает веб-сервер и агент начислений и блокирует до отмены ctx
+// или падения одного из них. Пул соединений закрывается после остановки обоих.
 func Run(ctx context.Context, opts *options.AppOptions) error {
-	migrations.RunUpMigration(opts.DatabaseURI)
+	if err := migrations.RunUpMigration(opts.DatabaseURI); err != nil {
+		return err
+	}
 	conn, err := pgxpool.New(ctx, opts.DatabaseURI)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
 
+	orderStore, err := store.NewOrderStore(conn)
 	if err != nil {
 		return err
 	}
-	ws, err := webserver.NewWebServer(conn, opts)
+	partners, err := opts.Partners()
 	if err != nil {
-		fmt.Printf("%e", err)
 		return err
 	}
-	context := context.Background()
-	orderStore, err := store.NewOrderStore(conn)
+	retryPolicy := accrualagent.RetryPolicy{
+		Base:        opts.AccrualBackoffBase,
+		Max:         opts.AccrualBackoffMax,
+		MaxAttempts: opts.AccrualMaxAttempts,
+		MaxAge:      opts.AccrualMaxOrderAge,
+		Poll:        opts.AccrualPollInterval,
+	}
+	a, err := accrualagent.NewAccrualAgent(partners, nil, orderStore, 1, retryPolicy)
+	if err != nil {
+		return err
+	}
+	ws, err := webserver.NewWebServer(conn, opts, a)
 	if err != nil {
 		return err
 	}
-	a := accrualagent.NewAccrualAgent(opts.AccrualSystemAddr, orderStore, 1)
-	go a.Start(context)
-	go ws.Start()
-	<-ctx.Done()
-	return nil
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		a.Start(gctx)
+		return nil
+	})
+	g.Go(ws.Start)
+	g.Go(func() error {
+		<-gctx.Done()
+		logger.Log.Info("shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return ws.Shutdown(shutdownCtx)
+	})
+	return g.Wait()
 }
@@ -0,0 +1,58 @@
+package httperr
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ShvetsovYura/oygophermart/internal/logger"
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Error описывает ответ API с ошибкой: http-статус и машиночитаемый код
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details any
+}
+
+var ErrInternal = New(http.StatusInternalServerError, "internal_error", "internal server error")
+
+func New(status int, code string, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// WithDetails возвращает копию ошибки с уточнением, исходная ошибка не меняется
+func (e *Error) WithDetails(details any) *Error {
+	cp := *e
+	cp.Details = details
+	return &cp
+}
+
+// Write отправляет ошибку в общем формате; ошибки не типа *Error отдаются как 500 без подробностей
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		logger.Log.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+		apiErr = ErrInternal
+	}
+	resp, mErr := json.Marshal(models.ErrorResp{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		Details:   apiErr.Details,
+		RequestID: middleware.GetReqID(r.Context()),
+	})
+	if mErr != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.Status)
+	w.Write(resp)
+}
@@ -0,0 +1,155 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/store"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type disputeFixture struct {
+	pool      *pgxpool.Pool
+	orders    *store.OrderStore
+	disputes  *store.DisputeStore
+	owner     int64
+	claimant  int64
+	admin     int64
+	disputeID int64
+}
+
+// newDisputeFixture создает заказ владельца с начислением 100 и спор заявителя по нему
+func newDisputeFixture(t *testing.T) *disputeFixture {
+	t.Helper()
+	ctx := context.Background()
+	f := &disputeFixture{pool: testPool(t)}
+	var err error
+	f.orders, err = store.NewOrderStore(f.pool)
+	require.NoError(t, err)
+	f.disputes, err = store.NewDisputeStore(f.pool)
+	require.NoError(t, err)
+	f.owner = addUser(t, f.pool, "pipa")
+	f.claimant = addUser(t, f.pool, "popa")
+	f.admin = addUser(t, f.pool, "admin")
+
+	accrual := 100.0
+	require.NoError(t, f.orders.AddNewOrder(ctx, f.owner, "12345678903"))
+	require.NoError(t, f.orders.UpdateOrdersStatus(ctx,
+		models.AccrualResult{OrderID: "12345678903", Status: "PROCESSED", Accrual: &accrual}))
+	f.disputeID, err = f.disputes.AddDispute(ctx, "12345678903", f.claimant, "receipt")
+	require.NoError(t, err)
+	return f
+}
+
+func (f *disputeFixture) orderOwner(t *testing.T) int64 {
+	t.Helper()
+	var userID int64
+	require.NoError(t, f.pool.QueryRow(context.Background(), `select user_id from "order" where id = $1`, "12345678903").Scan(&userID))
+	return userID
+}
+
+func (f *disputeFixture) balance(userID int64) float64 {
+	return f.orders.GetUserBalance(context.Background(), uint64(userID)).Balance
+}
+
+func TestDisputeReassign(t *testing.T) {
+	f := newDisputeFixture(t)
+	ctx := context.Background()
+
+	require.NoError(t, f.disputes.ResolveDispute(ctx, f.disputeID, f.admin, true, "receipt checked"))
+	assert.Equal(t, f.claimant, f.orderOwner(t))
+	assert.Equal(t, 0.0, f.balance(f.owner))
+	assert.Equal(t, 100.0, f.balance(f.claimant))
+
+	disputes, err := f.disputes.GetDisputes(ctx, store.DisputeReassigned, nil)
+	require.NoError(t, err)
+	require.Len(t, disputes, 1)
+	assert.Equal(t, f.disputeID, disputes[0].ID)
+
+	err = f.disputes.ResolveDispute(ctx, f.disputeID, f.admin, false, "")
+	assert.ErrorIs(t, err, store.ErrDisputeAlreadyResolved)
+}
+
+func TestDisputeReject(t *testing.T) {
+	f := newDisputeFixture(t)
+	ctx := context.Background()
+
+	require.NoError(t, f.disputes.ResolveDispute(ctx, f.disputeID, f.admin, false, ""))
+	assert.Equal(t, f.owner, f.orderOwner(t))
+	assert.Equal(t, 100.0, f.balance(f.owner))
+	assert.Equal(t, 0.0, f.balance(f.claimant))
+
+	disputes, err := f.disputes.GetDisputes(ctx, store.DisputeRejected, &f.claimant)
+	require.NoError(t, err)
+	require.Len(t, disputes, 1)
+}
+
+func TestDisputeStaleOwner(t *testing.T) {
+	f := newDisputeFixture(t)
+	ctx := context.Background()
+
+	// заказ сменил владельца после открытия спора
+	other := addUser(t, f.pool, "pupa")
+	_, err := f.pool.Exec(ctx, `update "order" set user_id = $2 where id = $1`, "12345678903", other)
+	require.NoError(t, err)
+
+	err = f.disputes.ResolveDispute(ctx, f.disputeID, f.admin, true, "")
+	assert.ErrorIs(t, err, store.ErrDisputeStale)
+	assert.Equal(t, other, f.orderOwner(t))
+	disputes, err := f.disputes.GetDisputes(ctx, store.DisputeOpen, nil)
+	require.NoError(t, err)
+	assert.Len(t, disputes, 1)
+}
+
+func TestDisputeOwnOrder(t *testing.T) {
+	f := newDisputeFixture(t)
+
+	_, err := f.disputes.AddDispute(context.Background(), "12345678903", f.owner, "receipt")
+	assert.ErrorIs(t, err, store.ErrDisputeOwnOrder)
+}
+
+func TestDisputeWithdrawalOrder(t *testing.T) {
+	f := newDisputeFixture(t)
+	ctx := context.Background()
+
+	require.NoError(t, f.orders.Withdraw(ctx, "2377225624", f.owner, 10))
+	_, err := f.disputes.AddDispute(ctx, "2377225624", f.claimant, "receipt")
+	assert.ErrorIs(t, err, store.ErrDisputeWithdrawalOrder)
+	assert.Equal(t, 90.0, f.balance(f.owner))
+}
+
+func TestDisputeReassignSpentAccrual(t *testing.T) {
+	f := newDisputeFixture(t)
+	ctx := context.Background()
+
+	// владелец уже потратил часть начисления, сторно увело бы баланс в минус
+	require.NoError(t, f.orders.Withdraw(ctx, "2377225624", f.owner, 60))
+	err := f.disputes.ResolveDispute(ctx, f.disputeID, f.admin, true, "")
+	assert.ErrorIs(t, err, store.ErrDisputeInsufficientFunds)
+	assert.Equal(t, f.owner, f.orderOwner(t))
+	assert.Equal(t, 40.0, f.balance(f.owner))
+	assert.Equal(t, 0.0, f.balance(f.claimant))
+	disputes, err := f.disputes.GetDisputes(ctx, store.DisputeOpen, nil)
+	require.NoError(t, err)
+	assert.Len(t, disputes, 1)
+}
+
+func TestCancelDisputedOrder(t *testing.T) {
+	f := newDisputeFixture(t)
+	ctx := context.Background()
+
+	require.NoError(t, f.orders.AddNewOrder(ctx, f.owner, "79927398713"))
+	disputeID, err := f.disputes.AddDispute(ctx, "79927398713", f.claimant, "receipt")
+	require.NoError(t, err)
+	err = f.orders.CancelOrder(ctx, "79927398713", f.owner)
+	assert.ErrorIs(t, err, store.ErrOrderDisputed)
+
+	// после разрешения спора заказ снова можно отменить
+	require.NoError(t, f.disputes.ResolveDispute(ctx, disputeID, f.admin, false, ""))
+	assert.NoError(t, f.orders.CancelOrder(ctx, "79927398713", f.owner))
+}
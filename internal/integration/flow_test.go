@@ -0,0 +1,233 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	accrualagent "github.com/ShvetsovYura/oygophermart/internal/accrual_agent"
+	accrualmock "github.com/ShvetsovYura/oygophermart/internal/accrual_mock"
+	"github.com/ShvetsovYura/oygophermart/internal/logger"
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/options"
+	"github.com/ShvetsovYura/oygophermart/internal/store"
+	"github.com/ShvetsovYura/oygophermart/internal/webserver"
+	"github.com/ShvetsovYura/oygophermart/migrations"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testApp struct {
+	url    string
+	client *http.Client
+	pool   *pgxpool.Pool
+}
+
+// startApp поднимает роутер, хранилища и агент начислений против заглушки системы начислений
+func startApp(t *testing.T) *testApp {
+	t.Helper()
+	logger.InitLogger("error")
+	dsn := testDatabase(t)
+	require.NoError(t, migrations.RunUpMigration(dsn))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+
+	mock, err := accrualmock.NewServer(accrualmock.Options{})
+	require.NoError(t, err)
+	accrual := httptest.NewServer(mock.Handler())
+
+	opts := &options.AppOptions{
+		DatabaseURI:       dsn,
+		AccrualSystemAddr: accrual.URL,
+		ReferralReward:    50,
+		MaxReferrals:      10,
+	}
+	partners, err := opts.Partners()
+	require.NoError(t, err)
+	orderStore, err := store.NewOrderStore(pool)
+	require.NoError(t, err)
+	agent, err := accrualagent.NewAccrualAgent(partners, nil, orderStore, 1, accrualagent.RetryPolicy{
+		Base: 100 * time.Millisecond,
+		Max:  time.Second,
+	})
+	require.NoError(t, err)
+	ws, err := webserver.NewWebServer(pool, opts, agent)
+	require.NoError(t, err)
+	srv := httptest.NewServer(ws.Handler())
+
+	stopped := make(chan struct{})
+	go func() {
+		agent.Start(ctx)
+		close(stopped)
+	}()
+	t.Cleanup(func() {
+		srv.Close()
+		cancel()
+		<-stopped
+		accrual.Close()
+		pool.Close()
+	})
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	return &testApp{url: srv.URL, client: &http.Client{Jar: jar}, pool: pool}
+}
+
+func (a *testApp) do(t *testing.T, method string, path string, contentType string, body []byte) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, a.url+path, bytes.NewReader(body))
+	require.NoError(t, err)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := a.client.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func (a *testApp) postJSON(t *testing.T, path string, v any) *http.Response {
+	t.Helper()
+	body, err := json.Marshal(v)
+	require.NoError(t, err)
+	return a.do(t, http.MethodPost, path, "application/json", body)
+}
+
+func (a *testApp) balance(t *testing.T) models.BalanceResp {
+	t.Helper()
+	resp := a.do(t, http.MethodGet, "/api/user/balance", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var balance models.BalanceResp
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&balance))
+	return balance
+}
+
+func TestRegisterUploadAccrueWithdraw(t *testing.T) {
+	app := startApp(t)
+
+	resp := app.postJSON(t, "/api/user/register", models.UserReq{Login: "pipa", Password: "secret"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = app.do(t, http.MethodPost, "/api/user/orders", "text/plain", []byte("12345678903"))
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	resp = app.do(t, http.MethodPost, "/api/user/orders", "text/plain", []byte("12345678903"))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// заглушка проводит заказ через REGISTERED и PROCESSING к PROCESSED с начислением 500
+	require.Eventually(t, func() bool {
+		return app.balance(t).Current == 500
+	}, 20*time.Second, 200*time.Millisecond)
+
+	resp = app.do(t, http.MethodGet, "/api/user/orders", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var orders []models.OrderGroupedModel
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&orders))
+	require.Len(t, orders, 1)
+	assert.Equal(t, "PROCESSED", orders[0].Status)
+	assert.Equal(t, 500.0, *orders[0].Accrual)
+
+	resp = app.postJSON(t, "/api/user/balance/withdraw", models.WithdrawReq{OrderID: "2377225624", Sum: 200})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = app.postJSON(t, "/api/user/balance/withdraw", models.WithdrawReq{OrderID: "9278923470", Sum: 1000})
+	assert.Equal(t, http.StatusPaymentRequired, resp.StatusCode)
+
+	balance := app.balance(t)
+	assert.Equal(t, 300.0, balance.Current)
+	assert.Equal(t, 200.0, balance.Withdrawn)
+
+	resp = app.do(t, http.MethodGet, "/api/user/withdrawals", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var transitions int
+	err := app.pool.QueryRow(context.Background(),
+		`select count(*) from order_status_history where order_id = $1`, "12345678903").Scan(&transitions)
+	require.NoError(t, err)
+	assert.Equal(t, 2, transitions)
+}
+
+func TestCancelOrderSoftDeletesAndFreesNumber(t *testing.T) {
+	app := startApp(t)
+	ctx := context.Background()
+
+	resp := app.postJSON(t, "/api/user/register", models.UserReq{Login: "pipa", Password: "secret"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = app.do(t, http.MethodPost, "/api/user/orders", "text/plain", []byte("79927398713"))
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	resp = app.do(t, http.MethodDelete, "/api/user/orders/79927398713", "", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// отмененный заказ остается в таблице для аудита
+	var status string
+	var deleted bool
+	err := app.pool.QueryRow(ctx,
+		`select status, deleted_at is not null from "order" where id = $1`, "79927398713").Scan(&status, &deleted)
+	require.NoError(t, err)
+	assert.Equal(t, "CANCELLED", status)
+	assert.True(t, deleted)
+	var jobs int
+	err = app.pool.QueryRow(ctx, `select count(*) from accrual_job where order_id = $1`, "79927398713").Scan(&jobs)
+	require.NoError(t, err)
+	assert.Equal(t, 0, jobs)
+
+	// номер освобожден: другой пользователь может его загрузить
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	app.client.Jar = jar
+	resp = app.postJSON(t, "/api/user/register", models.UserReq{Login: "popa", Password: "secret"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = app.do(t, http.MethodPost, "/api/user/orders", "text/plain", []byte("79927398713"))
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	var rows int
+	err = app.pool.QueryRow(ctx, `select count(*) from "order" where id = $1`, "79927398713").Scan(&rows)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rows)
+}
+
+func TestPromoRedemption(t *testing.T) {
+	app := startApp(t)
+	ctx := context.Background()
+
+	resp := app.postJSON(t, "/api/user/register", models.UserReq{Login: "pipa", Password: "secret"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = app.postJSON(t, "/api/admin/promo", models.PromoReq{Code: "SPRING", Value: 50})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, err := app.pool.Exec(ctx, `update "user" set is_admin = true where login = $1`, "pipa")
+	require.NoError(t, err)
+	maxRedemptions := 1
+	resp = app.postJSON(t, "/api/admin/promo", models.PromoReq{Code: "SPRING", Value: 50, MaxRedemptions: &maxRedemptions})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = app.postJSON(t, "/api/user/promo", models.PromoRedeemReq{Code: "SPRING"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var redeemed models.PromoRedeemResp
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&redeemed))
+	assert.Equal(t, 50.0, redeemed.Value)
+	assert.Equal(t, 50.0, app.balance(t).Current)
+
+	// повторное погашение тем же пользователем упирается в лимит на пользователя
+	resp = app.postJSON(t, "/api/user/promo", models.PromoRedeemReq{Code: "SPRING"})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, 50.0, app.balance(t).Current)
+
+	// другой пользователь упирается в общий лимит
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	app.client.Jar = jar
+	resp = app.postJSON(t, "/api/user/register", models.UserReq{Login: "popa", Password: "secret"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = app.postJSON(t, "/api/user/promo", models.PromoRedeemReq{Code: "SPRING"})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, 0.0, app.balance(t).Current)
+}
@@ -0,0 +1,102 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ShvetsovYura/oygophermart/internal/logger"
+	"github.com/ShvetsovYura/oygophermart/migrations"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testDatabase возвращает строку подключения к пустой базе.
+// При заданном TEST_DATABASE_URI на этом сервере создается отдельная база,
+// иначе во временном каталоге поднимается кластер через initdb/pg_ctl.
+func testDatabase(t *testing.T) string {
+	t.Helper()
+	if uri := os.Getenv("TEST_DATABASE_URI"); uri != "" {
+		return createDatabase(t, uri)
+	}
+	initdb, err := exec.LookPath("initdb")
+	if err != nil {
+		t.Skip("TEST_DATABASE_URI is not set and initdb is not found")
+	}
+	pgCtl := filepath.Join(filepath.Dir(initdb), "pg_ctl")
+
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data")
+	out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "--auth=trust", "--no-sync").CombinedOutput()
+	if err != nil {
+		t.Fatalf("initdb: %v\n%s", err, out)
+	}
+	port := freePort(t)
+	opts := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off", port, dir)
+	out, err = exec.Command(pgCtl, "-D", data, "-o", opts, "-l", filepath.Join(dir, "log"), "-w", "start").CombinedOutput()
+	if err != nil {
+		t.Fatalf("pg_ctl start: %v\n%s", err, out)
+	}
+	t.Cleanup(func() {
+		exec.Command(pgCtl, "-D", data, "-m", "immediate", "stop").Run()
+	})
+	return fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port)
+}
+
+// testPool возвращает пул к базе с примененными миграциями
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	logger.InitLogger("error")
+	dsn := testDatabase(t)
+	if err := migrations.RunUpMigration(dsn); err != nil {
+		t.Fatalf("migrations: %v", err)
+	}
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func createDatabase(t *testing.T, uri string) string {
+	t.Helper()
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, uri)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	name := fmt.Sprintf("gophermart_test_%d", time.Now().UnixNano())
+	if _, err = conn.Exec(ctx, "create database "+name); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Exec(ctx, "drop database if exists "+name+" with (force)")
+		conn.Close(ctx)
+	})
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("parse uri: %v", err)
+	}
+	u.Path = "/" + name
+	return u.String()
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
@@ -0,0 +1,208 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/store"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addUser создает пользователя напрямую через хранилище и возвращает его id
+func addUser(t *testing.T, pool *pgxpool.Pool, login string) int64 {
+	t.Helper()
+	ctx := context.Background()
+	users, err := store.NewUserStore(pool)
+	require.NoError(t, err)
+	require.NoError(t, users.AddUser(ctx, login, "hash", login+"-code", "", nil))
+	user, err := users.GetUserByLogin(ctx, login)
+	require.NoError(t, err)
+	return user.ID
+}
+
+func jobAttempts(t *testing.T, pool *pgxpool.Pool, orderID string) int {
+	t.Helper()
+	var attempts int
+	err := pool.QueryRow(context.Background(), `select attempts from accrual_job where order_id = $1`, orderID).Scan(&attempts)
+	require.NoError(t, err)
+	return attempts
+}
+
+func TestAccrualJobCountsOnlyFailedPolls(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	orders, err := store.NewOrderStore(pool)
+	require.NoError(t, err)
+	userID := addUser(t, pool, "pipa")
+	require.NoError(t, orders.AddNewOrder(ctx, userID, "12345678903"))
+
+	// долгий PROCESSING: взятие и успешный опрос счетчик не увеличивают
+	for i := 0; i < 5; i++ {
+		_, err = pool.Exec(ctx, `update accrual_job set next_attempt_at = now()`)
+		require.NoError(t, err)
+		jobs, err := orders.ClaimAccrualJobs(ctx, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, 0, jobs[0].Attempts)
+		require.NoError(t, orders.UpdateOrdersStatus(ctx, models.AccrualResult{OrderID: "12345678903", Status: "PROCESSING"}))
+	}
+
+	require.NoError(t, orders.FailAccrualJobs(ctx, models.AccrualJobFailure{OrderID: "12345678903", Error: "timeout"}))
+	assert.Equal(t, 1, jobAttempts(t, pool, "12345678903"))
+	require.NoError(t, orders.FailAccrualJobs(ctx, models.AccrualJobFailure{OrderID: "12345678903", Error: "429", Postponed: true}))
+	assert.Equal(t, 1, jobAttempts(t, pool, "12345678903"))
+
+	require.NoError(t, orders.UpdateOrdersStatus(ctx, models.AccrualResult{OrderID: "12345678903", Status: "PROCESSING"}))
+	assert.Equal(t, 0, jobAttempts(t, pool, "12345678903"))
+}
+
+func TestAccrualJobRescheduledAfterPollInterval(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	orders, err := store.NewOrderStore(pool)
+	require.NoError(t, err)
+	userID := addUser(t, pool, "pipa")
+	require.NoError(t, orders.AddNewOrder(ctx, userID, "12345678903"))
+
+	jobs, err := orders.ClaimAccrualJobs(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.NoError(t, orders.UpdateOrdersStatus(ctx,
+		models.AccrualResult{OrderID: "12345678903", Status: "PROCESSING", NextPoll: time.Minute}))
+
+	// заказ в обработке не опрашивается на каждом тике
+	jobs, err = orders.ClaimAccrualJobs(ctx, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+}
+
+func TestAddUserWithReferralLimit(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	users, err := store.NewUserStore(pool)
+	require.NoError(t, err)
+	referrerID := addUser(t, pool, "pipa")
+	ref := &models.ReferralModel{ReferrerID: referrerID, Reward: 50, MaxReferrals: 1}
+
+	require.NoError(t, users.AddUser(ctx, "popa", "hash", "popa-code", "", ref))
+	referee, err := users.GetUserByLogin(ctx, "popa")
+	require.NoError(t, err)
+	require.NotNil(t, referee)
+	cnt, err := users.CountReferrals(ctx, referrerID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, cnt)
+
+	// лимит исчерпан: ни пользователь, ни приглашение не сохраняются
+	err = users.AddUser(ctx, "pupa", "hash", "pupa-code", "", ref)
+	assert.ErrorIs(t, err, store.ErrReferralLimitReached)
+	user, err := users.GetUserByLogin(ctx, "pupa")
+	require.NoError(t, err)
+	assert.Nil(t, user)
+	cnt, err = users.CountReferrals(ctx, referrerID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, cnt)
+}
+
+func TestAddUserWithReferralConcurrent(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	users, err := store.NewUserStore(pool)
+	require.NoError(t, err)
+	referrerID := addUser(t, pool, "pipa")
+	ref := &models.ReferralModel{ReferrerID: referrerID, Reward: 50, MaxReferrals: 3}
+
+	const n = 10
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			login := fmt.Sprintf("user-%d", i)
+			errs <- users.AddUser(ctx, login, "hash", login+"-code", "", ref)
+		}(i)
+	}
+	created := 0
+	for i := 0; i < n; i++ {
+		if err := <-errs; err == nil {
+			created++
+		} else {
+			assert.ErrorIs(t, err, store.ErrReferralLimitReached)
+		}
+	}
+	assert.Equal(t, 3, created)
+	var total int
+	require.NoError(t, pool.QueryRow(ctx, `select count(*) from "user" where login like 'user-%'`).Scan(&total))
+	assert.Equal(t, 3, total)
+}
+
+func TestAddUserUniqueViolation(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	users, err := store.NewUserStore(pool)
+	require.NoError(t, err)
+	addUser(t, pool, "pipa")
+
+	err = users.AddUser(ctx, "pipa", "hash", "other-code", "", nil)
+	assert.ErrorIs(t, err, store.ErrLoginAlreadyExists)
+	err = users.AddUser(ctx, "popa", "hash", "pipa-code", "", nil)
+	assert.ErrorIs(t, err, store.ErrReferralCodeAlreadyExists)
+}
+
+func TestUpdateOrdersStatusRollsBackFailedBatch(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	orders, err := store.NewOrderStore(pool)
+	require.NoError(t, err)
+	userID := addUser(t, pool, "pipa")
+	require.NoError(t, orders.AddNewOrder(ctx, userID, "12345678903"))
+	require.NoError(t, orders.AddNewOrder(ctx, userID, "2377225624"))
+
+	// сохранение опроса второго заказа падает
+	_, err = pool.Exec(ctx, `
+		create function fail_poll() returns trigger as $$
+		begin
+			if new.order_id = '2377225624' then
+				raise exception 'poll rejected';
+			end if;
+			return new;
+		end;
+		$$ language plpgsql;
+		create trigger fail_poll before insert on order_poll for each row execute function fail_poll();
+	`)
+	require.NoError(t, err)
+
+	accrual := 500.0
+	batch := []models.AccrualResult{
+		{OrderID: "12345678903", Status: "PROCESSED", Accrual: &accrual},
+		{OrderID: "2377225624", Status: "PROCESSED", Accrual: &accrual},
+	}
+	assert.Error(t, orders.UpdateOrdersStatus(ctx, batch...))
+
+	status := func(orderID string) string {
+		var s string
+		require.NoError(t, pool.QueryRow(ctx, `select status from "order" where id = $1`, orderID).Scan(&s))
+		return s
+	}
+	accrued := func() int {
+		var n int
+		require.NoError(t, pool.QueryRow(ctx, `select count(*) from loyalty where kind = 'ACCRUAL'`).Scan(&n))
+		return n
+	}
+	// пачка откатывается целиком, в том числе уже примененная первая запись
+	assert.Equal(t, "NEW", status("12345678903"))
+	assert.Equal(t, "NEW", status("2377225624"))
+	assert.Equal(t, 0, accrued())
+
+	// повтор той же пачки после устранения ошибки применяет обе записи
+	_, err = pool.Exec(ctx, `drop trigger fail_poll on order_poll`)
+	require.NoError(t, err)
+	require.NoError(t, orders.UpdateOrdersStatus(ctx, batch...))
+	assert.Equal(t, "PROCESSED", status("12345678903"))
+	assert.Equal(t, "PROCESSED", status("2377225624"))
+	assert.Equal(t, 2, accrued())
+}
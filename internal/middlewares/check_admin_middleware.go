@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ShvetsovYura/oygophermart/internal/httperr"
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+)
+
+var ErrAdminRequired = httperr.New(http.StatusForbidden, "admin_required", "admin access required")
+
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, userID uint64) (bool, error)
+}
+
+func CheckAdmin(c AdminChecker) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := r.Context().Value(models.UIDKey).(uint64)
+			if !ok {
+				httperr.Write(w, r, ErrUnauthorized)
+				return
+			}
+			isAdmin, err := c.IsAdmin(r.Context(), userID)
+			if err != nil {
+				httperr.Write(w, r, ErrUnauthorized)
+				return
+			}
+			if !isAdmin {
+				httperr.Write(w, r, ErrAdminRequired)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
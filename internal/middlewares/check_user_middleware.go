@@ -1,6 +1,12 @@
 package middlewares
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/ShvetsovYura/oygophermart/internal/httperr"
+)
+
+var ErrUnauthorized = httperr.New(http.StatusUnauthorized, "unauthorized", "authorization required")
 
 type Vaidator interface {
 	ValidateSign(token string) (bool, error)
@@ -11,16 +17,16 @@ func CheckAuthCookie(v Vaidator) func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			c, err := r.Cookie("token")
 			if err != nil {
-				http.Error(w, "error get auth cookies", http.StatusUnauthorized)
+				httperr.Write(w, r, ErrUnauthorized)
 				return
 			}
 			valid, err := v.ValidateSign(c.Value)
 			if err != nil {
-				http.Error(w, "error on check auth cookie", http.StatusInternalServerError)
+				httperr.Write(w, r, err)
 				return
 			}
 			if !valid {
-				http.Error(w, "not valid auth cookies", http.StatusUnauthorized)
+				httperr.Write(w, r, ErrUnauthorized)
 				return
 			}
 			next.ServeHTTP(w, r)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/ShvetsovYura/oygophermart/internal/httperr"
 	"github.com/ShvetsovYura/oygophermart/internal/models"
 )
 
@@ -19,13 +20,13 @@ func ExtractUserID(ex Extractor) func(next http.Handler) http.Handler {
 				userID, err := ex.ExtractUserID(c.Value)
 
 				if err != nil {
-					http.Error(w, "Unable get user", http.StatusInternalServerError)
+					httperr.Write(w, r, err)
 					return
 				}
 				ctx := context.WithValue(r.Context(), models.UIDKey, userID)
 				next.ServeHTTP(w, r.WithContext(ctx))
 			} else {
-				http.Error(w, "Unable get cookies", http.StatusBadRequest)
+				httperr.Write(w, r, ErrUnauthorized)
 				return
 			}
 
@@ -6,6 +6,7 @@ type OrderModel struct {
 	ID         string
 	UserID     uint64
 	Status     string
+	Partner    string
 	CreateedAt time.Time
 	UpdatedAt  time.Time
 }
@@ -21,10 +22,39 @@ type LoyaltyOrderModel struct {
 	UpdatedAt time.Time
 }
 
+type OrderDetailModel struct {
+	ID        string
+	Status    string
+	Accrual   *float64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type OrderPollModel struct {
+	Status   string
+	Accrual  *float64
+	PolledAt time.Time
+}
+
 type UserModel struct {
-	ID      int64
-	Login   string
-	PwdHash string
+	ID           int64
+	Login        string
+	PwdHash      string
+	IsAdmin      bool
+	ReferralCode string
+	RegisterIP   *string
+}
+
+// ReferralModel - приглашение, которое сохраняется вместе с новым пользователем
+type ReferralModel struct {
+	ReferrerID   int64
+	Reward       float64
+	MaxReferrals int
+}
+
+type ReferralStatsModel struct {
+	Invited  int
+	Rewarded int
 }
 
 type BalanceModel struct {
@@ -32,3 +62,118 @@ type BalanceModel struct {
 	Withdrawn float64
 	Balance   float64
 }
+
+type PromoCodeModel struct {
+	Code           string
+	Value          float64
+	ValidFrom      time.Time
+	ValidTo        *time.Time
+	MaxRedemptions *int
+	PerUserLimit   int
+	CreatedBy      int64
+	CreatedAt      time.Time
+}
+
+type TransferModel struct {
+	ID         int64
+	FromUserID int64
+	FromLogin  string
+	ToUserID   int64
+	ToLogin    string
+	Value      float64
+	CreatedAt  time.Time
+}
+
+type TransferLimits struct {
+	Sum   float64
+	Count int
+}
+
+type HistoryRecordModel struct {
+	ID        int64
+	Kind      string
+	OrderID   *string
+	Value     float64
+	Balance   float64
+	CreatedAt time.Time
+}
+
+type Cursor struct {
+	At time.Time
+	ID string
+}
+
+type HistoryFilter struct {
+	Kinds []string
+	From  *time.Time
+	To    *time.Time
+	After *Cursor
+	Limit int
+}
+
+type OrderFilter struct {
+	Statuses []string
+	From     *time.Time
+	To       *time.Time
+	After    *Cursor
+	Desc     bool
+	Limit    int
+}
+
+const (
+	BulkOrderAccepted         = "accepted"
+	BulkOrderDuplicateOwn     = "duplicate-own"
+	BulkOrderDuplicateForeign = "duplicate-foreign"
+	BulkOrderInvalid          = "invalid"
+)
+
+type ImportOrderModel struct {
+	Line       int
+	Login      string
+	OrderID    string
+	Status     string
+	Accrual    *float64
+	UploadedAt *time.Time
+}
+
+type ImportErrorModel struct {
+	Line    int
+	OrderID string
+	Error   string
+}
+
+type DisputeModel struct {
+	ID         int64
+	OrderID    string
+	ClaimantID int64
+	OwnerID    int64
+	Evidence   string
+	Status     string
+	Comment    *string
+	ResolvedBy *int64
+	CreatedAt  time.Time
+	ResolvedAt *time.Time
+}
+
+type NotificationModel struct {
+	ID        int64
+	Message   string
+	CreatedAt time.Time
+}
+
+type AccrualJobModel struct {
+	OrderID   string
+	Partner   string
+	Status    string
+	Attempts  int
+	CreatedAt time.Time
+}
+
+type AccrualJobFailure struct {
+	OrderID    string
+	Error      string
+	RetryAfter time.Duration
+	GiveUp     bool
+	// опрос не выполнялся (ограничение партнера), попытка не засчитывается
+	Postponed bool
+}
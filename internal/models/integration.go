@@ -1,7 +1,13 @@
 package models
 
+import "time"
+
 type AccrualResult struct {
 	OrderID string   `json:"order"`
 	Status  string   `json:"status"`
 	Accrual *float64 `json:"accrual,omitempty"`
+	// откуда получен статус, для order_status_history
+	Source string `json:"-"`
+	// через сколько опросить заказ снова, если статус не итоговый
+	NextPoll time.Duration `json:"-"`
 }
@@ -10,6 +10,7 @@ type OrderGroupedModel struct {
 	Status    string    `json:"status"`
 	Accrual   *float64  `json:"accrual,omitempty"`
 	UpdatedAt time.Time `json:"updated_at"`
+	CreatedAt time.Time `json:"-"`
 }
 
 func (m OrderModel) MarshalJSON() ([]byte, error) {
@@ -0,0 +1,64 @@
+package models
+
+type OrderStatus string
+
+const (
+	OrderNew        OrderStatus = "NEW"
+	OrderProcessing OrderStatus = "PROCESSING"
+	OrderInvalid    OrderStatus = "INVALID"
+	OrderProcessed  OrderStatus = "PROCESSED"
+	OrderUnknown    OrderStatus = "UNKNOWN"
+	OrderCancelled  OrderStatus = "CANCELLED"
+)
+
+// источники смены статуса в order_status_history
+const (
+	StatusSourcePoll    = "poll"
+	StatusSourceWebhook = "webhook"
+	StatusSourceAgent   = "agent"
+	StatusSourceUser    = "user"
+)
+
+// orderTransitions - допустимые переходы; PROCESSED, INVALID и CANCELLED конечные.
+// Из UNKNOWN заказ выводит только статус, присланный партнером.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderNew:        {OrderProcessing, OrderInvalid, OrderProcessed, OrderUnknown, OrderCancelled},
+	OrderProcessing: {OrderInvalid, OrderProcessed, OrderUnknown, OrderCancelled},
+	OrderUnknown:    {OrderProcessing, OrderInvalid, OrderProcessed},
+}
+
+// статусы системы начислений и соответствующие им статусы заказа
+var accrualStatuses = map[string]OrderStatus{
+	"REGISTERED": OrderProcessing,
+	"PROCESSING": OrderProcessing,
+	"INVALID":    OrderInvalid,
+	"PROCESSED":  OrderProcessed,
+}
+
+func (s OrderStatus) Valid() bool {
+	_, ok := orderTransitions[s]
+	return ok || s == OrderInvalid || s == OrderProcessed || s == OrderCancelled
+}
+
+func (s OrderStatus) Final() bool {
+	return len(orderTransitions[s]) == 0
+}
+
+// CanTransitionTo сообщает, допустим ли переход; повтор текущего статуса допустим
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	if s == next {
+		return true
+	}
+	for _, st := range orderTransitions[s] {
+		if st == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAccrualStatus переводит статус системы начислений в статус заказа
+func ParseAccrualStatus(status string) (OrderStatus, bool) {
+	s, ok := accrualStatuses[status]
+	return s, ok
+}
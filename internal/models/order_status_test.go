@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from     OrderStatus
+		to       OrderStatus
+		expected bool
+	}{
+		{from: OrderNew, to: OrderProcessing, expected: true},
+		{from: OrderProcessing, to: OrderProcessing, expected: true},
+		{from: OrderProcessing, to: OrderProcessed, expected: true},
+		{from: OrderProcessed, to: OrderProcessing, expected: false},
+		{from: OrderInvalid, to: OrderProcessed, expected: false},
+		{from: OrderUnknown, to: OrderProcessed, expected: true},
+		{from: OrderUnknown, to: OrderCancelled, expected: false},
+		{from: OrderCancelled, to: OrderNew, expected: false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.from.CanTransitionTo(test.to), "%s -> %s", test.from, test.to)
+	}
+
+	assert.True(t, OrderProcessed.Final())
+	assert.False(t, OrderUnknown.Final())
+	assert.False(t, OrderStatus("DONE").Valid())
+
+	st, ok := ParseAccrualStatus("REGISTERED")
+	assert.True(t, ok)
+	assert.Equal(t, OrderProcessing, st)
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type WithdrawReq struct {
 	OrderID string  `json:"order"`
 	Sum     float32 `json:"sum"`
@@ -8,4 +10,50 @@ type WithdrawReq struct {
 type UserReq struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
+	Referral string `json:"referral,omitempty"`
+}
+
+type PromoReq struct {
+	Code           string     `json:"code"`
+	Value          float64    `json:"value"`
+	ValidFrom      *time.Time `json:"valid_from,omitempty"`
+	ValidTo        *time.Time `json:"valid_to,omitempty"`
+	MaxRedemptions *int       `json:"max_redemptions,omitempty"`
+	PerUserLimit   int        `json:"per_user_limit,omitempty"`
+}
+
+type PromoRedeemReq struct {
+	Code string `json:"code"`
+}
+
+type TransferReq struct {
+	Login string  `json:"login"`
+	Sum   float64 `json:"sum"`
+}
+
+type HistoryQuery struct {
+	Types  []string
+	From   *time.Time
+	To     *time.Time
+	Cursor string
+	Limit  int
+}
+
+type OrderQuery struct {
+	Statuses []string
+	From     *time.Time
+	To       *time.Time
+	Cursor   string
+	Sort     string
+	Limit    int
+}
+
+type DisputeReq struct {
+	OrderID  string `json:"order"`
+	Evidence string `json:"evidence"`
+}
+
+type DisputeResolveReq struct {
+	Decision string `json:"decision"`
+	Comment  string `json:"comment"`
 }
@@ -12,3 +12,120 @@ type UserWithdrawalsResp struct {
 	Sum         float64   `json:"sum"`
 	ProcessedAt time.Time `json:"processed_at"`
 }
+
+type PromoRedeemResp struct {
+	Code  string  `json:"code"`
+	Value float64 `json:"value"`
+}
+
+type ReferralResp struct {
+	Code     string `json:"code"`
+	Invited  int    `json:"invited"`
+	Rewarded int    `json:"rewarded"`
+}
+
+type TransferResp struct {
+	Direction   string    `json:"direction"`
+	Login       string    `json:"login"`
+	Sum         float64   `json:"sum"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+type HistoryRecordResp struct {
+	Type        string    `json:"type"`
+	OrderID     *string   `json:"order,omitempty"`
+	Sum         float64   `json:"sum"`
+	Balance     float64   `json:"balance"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+type HistoryResp struct {
+	Items      []HistoryRecordResp `json:"items"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+type OrderPollResp struct {
+	Status   string    `json:"status"`
+	Accrual  *float64  `json:"accrual,omitempty"`
+	PolledAt time.Time `json:"polled_at"`
+}
+
+type OrderDetailResp struct {
+	OrderID    string          `json:"number"`
+	Status     string          `json:"status"`
+	Accrual    *float64        `json:"accrual,omitempty"`
+	UploadedAt time.Time       `json:"uploaded_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	Polls      []OrderPollResp `json:"polls"`
+}
+
+type BulkOrderResp struct {
+	OrderID string `json:"number"`
+	Result  string `json:"result"`
+}
+
+type ImportErrorResp struct {
+	Line    int    `json:"line"`
+	OrderID string `json:"number,omitempty"`
+	Error   string `json:"error"`
+}
+
+type ImportResp struct {
+	DryRun   bool              `json:"dry_run"`
+	Total    int               `json:"total"`
+	Imported int               `json:"imported"`
+	Errors   []ImportErrorResp `json:"errors"`
+}
+
+type DisputeResp struct {
+	ID         int64      `json:"id"`
+	OrderID    string     `json:"order"`
+	ClaimantID int64      `json:"claimant_id,omitempty"`
+	OwnerID    int64      `json:"owner_id,omitempty"`
+	Evidence   string     `json:"evidence"`
+	Status     string     `json:"status"`
+	Comment    *string    `json:"comment,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+type NotificationResp struct {
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type LimiterStateResp struct {
+	Partner      string     `json:"partner"`
+	Rate         float64    `json:"rate"`
+	MaxRate      float64    `json:"max_rate"`
+	Tokens       float64    `json:"tokens"`
+	BlockedUntil *time.Time `json:"blocked_until,omitempty"`
+	Throttled    int64      `json:"throttled"`
+}
+
+const (
+	HealthOK          = "ok"
+	HealthDegraded    = "degraded"
+	HealthUnavailable = "unavailable"
+)
+
+type BreakerStateResp struct {
+	Partner  string     `json:"partner"`
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	RetryAt  *time.Time `json:"retry_at,omitempty"`
+}
+
+type HealthResp struct {
+	Status   string             `json:"status"`
+	DB       string             `json:"db"`
+	Partners []BreakerStateResp `json:"accrual"`
+}
+
+type ErrorResp struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
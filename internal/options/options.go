@@ -1,21 +1,78 @@
 package options
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
+	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v10"
 )
 
 type AppOptions struct {
-	RunAddr           string `env:"RUN_ADDRESS"`
-	DatabaseURI       string `env:"DATABASE_URI"`
-	AccrualSystemAddr string `env:"ACCRUAL_SYSTEM_ADDRESS"`
+	RunAddr           string  `env:"RUN_ADDRESS"`
+	DatabaseURI       string  `env:"DATABASE_URI"`
+	AccrualSystemAddr string  `env:"ACCRUAL_SYSTEM_ADDRESS"`
+	ReferralReward    float64 `env:"REFERRAL_REWARD"`
+	MaxReferrals      int     `env:"REFERRAL_MAX"`
+	TransferDailySum  float64 `env:"TRANSFER_DAILY_SUM"`
+	TransferDailyMax  int     `env:"TRANSFER_DAILY_MAX"`
+	OrderSchemes      string  `env:"ORDER_NUMBER_SCHEMES"`
+	AccrualPartners   string  `env:"ACCRUAL_PARTNERS"`
+
+	AccrualBackoffBase  time.Duration `env:"ACCRUAL_BACKOFF_BASE"`
+	AccrualBackoffMax   time.Duration `env:"ACCRUAL_BACKOFF_MAX"`
+	AccrualMaxAttempts  int           `env:"ACCRUAL_MAX_ATTEMPTS"`
+	AccrualMaxOrderAge  time.Duration `env:"ACCRUAL_MAX_ORDER_AGE"`
+	AccrualPollInterval time.Duration `env:"ACCRUAL_POLL_INTERVAL"`
+
+	AccrualWebhookSecret string `env:"ACCRUAL_WEBHOOK_SECRET"`
+}
+
+const DefaultPartner = "default"
+
+var ErrPartnersNotValid = errors.New("accrual partners config is not valid")
+
+// PartnerOptions описывает систему начислений партнера.
+// Заказы направляются партнеру по самому длинному совпавшему префиксу номера.
+type PartnerOptions struct {
+	Name     string   `json:"name"`
+	URL      string   `json:"url"`
+	Prefixes []string `json:"prefixes"`
+	Scheme   string   `json:"scheme,omitempty"`
+	Token    string   `json:"token,omitempty"`
+	User     string   `json:"user,omitempty"`
+	Password string   `json:"password,omitempty"`
+	RPS      float64  `json:"rps,omitempty"`
+
+	Headers            map[string]string `json:"headers,omitempty"`
+	Timeout            string            `json:"timeout,omitempty"`
+	MaxBodySize        int64             `json:"max_body_size,omitempty"`
+	CACert             string            `json:"ca_cert,omitempty"`
+	ClientCert         string            `json:"client_cert,omitempty"`
+	ClientKey          string            `json:"client_key,omitempty"`
+	InsecureSkipVerify bool              `json:"insecure_skip_verify,omitempty"`
+	// секрет подписи статусов, которые партнер присылает на webhook
+	WebhookSecret string `json:"webhook_secret,omitempty"`
 }
 
 func (o *AppOptions) ParseArgs() {
 	flag.StringVar(&o.RunAddr, "a", ":3001", "server endpoint address")
 	flag.StringVar(&o.DatabaseURI, "d", "", "db connection string")
 	flag.StringVar(&o.AccrualSystemAddr, "r", "localhost:8080", "accrual service address")
+	flag.Float64Var(&o.ReferralReward, "referral-reward", 50, "points credited to both referrer and referee")
+	flag.IntVar(&o.MaxReferrals, "referral-max", 10, "max referrals per user")
+	flag.Float64Var(&o.TransferDailySum, "transfer-daily-sum", 1000, "max points a user can transfer per day, 0 - unlimited")
+	flag.IntVar(&o.TransferDailyMax, "transfer-daily-max", 5, "max transfers a user can make per day, 0 - unlimited")
+	flag.StringVar(&o.OrderSchemes, "order-schemes", "", "order number schemes by prefix, e.g. AB=alnum,978=mod11")
+	flag.StringVar(&o.AccrualPartners, "partners", "", "accrual partners as JSON array")
+	flag.DurationVar(&o.AccrualBackoffBase, "accrual-backoff-base", time.Second, "initial delay before repeating a failed accrual poll")
+	flag.DurationVar(&o.AccrualBackoffMax, "accrual-backoff-max", 10*time.Minute, "max delay between accrual polls of an order")
+	flag.IntVar(&o.AccrualMaxAttempts, "accrual-max-attempts", 50, "failed accrual polls in a row before order becomes UNKNOWN, 0 - unlimited")
+	flag.DurationVar(&o.AccrualPollInterval, "accrual-poll-interval", 10*time.Second, "delay before polling again an order that is still being processed")
+	flag.DurationVar(&o.AccrualMaxOrderAge, "accrual-max-order-age", 72*time.Hour, "order age after which polling gives up, 0 - unlimited")
+	flag.StringVar(&o.AccrualWebhookSecret, "webhook-secret", "", "HMAC secret of accrual webhook for default partner, empty - disabled")
 	flag.Parse()
 }
 
@@ -25,3 +82,39 @@ func (o *AppOptions) ParseEnv() error {
 	}
 	return nil
 }
+
+// Partners возвращает партнеров из ACCRUAL_PARTNERS; система из ACCRUAL_SYSTEM_ADDRESS
+// всегда добавляется как партнер по умолчанию для номеров без подходящего префикса.
+func (o *AppOptions) Partners() ([]PartnerOptions, error) {
+	var partners []PartnerOptions
+	if o.AccrualPartners != "" {
+		if err := json.Unmarshal([]byte(o.AccrualPartners), &partners); err != nil {
+			return nil, err
+		}
+	}
+	names := make(map[string]bool, len(partners)+1)
+	for _, p := range partners {
+		if p.Name == "" || p.URL == "" || names[p.Name] || p.RPS < 0 {
+			return nil, ErrPartnersNotValid
+		}
+		names[p.Name] = true
+	}
+	if !names[DefaultPartner] {
+		partners = append(partners, PartnerOptions{Name: DefaultPartner, URL: o.AccrualSystemAddr, WebhookSecret: o.AccrualWebhookSecret})
+	}
+	return partners, nil
+}
+
+// PartnerForOrder возвращает партнера с самым длинным совпавшим префиксом номера,
+// иначе партнера по умолчанию.
+func PartnerForOrder(partners []PartnerOptions, orderID string) string {
+	name, longest := DefaultPartner, -1
+	for _, p := range partners {
+		for _, prefix := range p.Prefixes {
+			if len(prefix) > longest && strings.HasPrefix(orderID, prefix) {
+				name, longest = p.Name, len(prefix)
+			}
+		}
+	}
+	return name
+}
@@ -0,0 +1,99 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ShvetsovYura/oygophermart/internal/httperr"
+	"github.com/ShvetsovYura/oygophermart/internal/services"
+	"github.com/ShvetsovYura/oygophermart/internal/store"
+)
+
+var (
+	errBodyNotValid        = httperr.New(http.StatusBadRequest, "body_not_valid", "request body is not valid")
+	errContentTypeNotValid = httperr.New(http.StatusBadRequest, "content_type_not_valid", "content type is not supported")
+	errQueryNotValid       = httperr.New(http.StatusBadRequest, "query_not_valid", "query parameter is not valid")
+	errOrderNumberNotValid = httperr.New(http.StatusUnprocessableEntity, "order_number_not_valid", "order number is not valid")
+	errNotFound            = httperr.New(http.StatusNotFound, "not_found", "resource not found")
+	errMethodNotAllowed    = httperr.New(http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+	errBodyTooLarge        = httperr.New(http.StatusRequestEntityTooLarge, "body_too_large", "request body is too large")
+)
+
+// errorMapping сопоставляет ошибки сервисов и хранилищ с ответами API.
+// Порядок важен: используется первое совпадение по errors.Is
+var errorMapping = []struct {
+	err    error
+	apiErr *httperr.Error
+}{
+	{services.ErrUserAlreadyExists, httperr.New(http.StatusConflict, "user_already_exists", "user already exists")},
+	{services.ErrUserNotFound, httperr.New(http.StatusUnauthorized, "invalid_credentials", "login or password is not valid")},
+	{services.ErrNotValidLoginOrPassword, httperr.New(http.StatusUnauthorized, "invalid_credentials", "login or password is not valid")},
+	{services.ErrReferralNotFound, httperr.New(http.StatusUnprocessableEntity, "referral_not_found", "referral code not found")},
+	{services.ErrReferralLimitReached, httperr.New(http.StatusUnprocessableEntity, "referral_limit_reached", "referrer has reached the referral limit")},
+	{store.ErrReferralLimitReached, httperr.New(http.StatusUnprocessableEntity, "referral_limit_reached", "referrer has reached the referral limit")},
+	{services.ErrSelfReferral, httperr.New(http.StatusUnprocessableEntity, "self_referral", "self referral is not allowed")},
+
+	{services.ErrOrderAlreadyAddedByAnotherUser, httperr.New(http.StatusConflict, "order_owned_by_another_user", "order has already been added by another user")},
+	{services.ErrBulkOrdersEmpty, httperr.New(http.StatusBadRequest, "orders_empty", "no orders to upload")},
+	{services.ErrBulkOrdersTooMany, httperr.New(http.StatusBadRequest, "orders_too_many", "too many orders to upload")},
+	{services.ErrFilterNotValid, httperr.New(http.StatusBadRequest, "filter_not_valid", "filter params are not valid")},
+	{services.ErrCursorNotValid, httperr.New(http.StatusBadRequest, "cursor_not_valid", "cursor is not valid")},
+	{store.ErrOrdersNotFoundInDB, httperr.New(http.StatusNotFound, "order_not_found", "order not found")},
+	{store.ErrOrderAlreadyExistsInDB, httperr.New(http.StatusUnprocessableEntity, "order_already_exists", "order already exists")},
+	{store.ErrOrderAlreadyProcessed, httperr.New(http.StatusConflict, "order_status_conflict", "order status does not allow the operation")},
+	{store.ErrOrderDisputed, httperr.New(http.StatusConflict, "order_disputed", "order has an open dispute")},
+	{services.ErrOrderStatusTransition, httperr.New(http.StatusConflict, "order_status_conflict", "order status does not allow the operation")},
+	{services.ErrImportNotValid, httperr.New(http.StatusBadRequest, "import_not_valid", "import file is not valid")},
+	{services.ErrExportKindNotValid, httperr.New(http.StatusNotFound, "export_kind_not_found", "export kind not found")},
+	{services.ErrExportFormatNotValid, httperr.New(http.StatusBadRequest, "export_format_not_valid", "export format is not valid")},
+
+	{services.ErrInsufficientFunds, httperr.New(http.StatusPaymentRequired, "insufficient_funds", "insufficient funds")},
+	{store.ErrInsufficientFundsInDB, httperr.New(http.StatusPaymentRequired, "insufficient_funds", "insufficient funds")},
+	{services.ErrTransferRecipientNotFound, httperr.New(http.StatusNotFound, "recipient_not_found", "transfer recipient not found")},
+	{services.ErrTransferToSelf, httperr.New(http.StatusBadRequest, "transfer_to_self", "transfer to self is not allowed")},
+	{services.ErrTransferNotValid, httperr.New(http.StatusBadRequest, "transfer_not_valid", "transfer sum must be positive")},
+	{store.ErrTransferDailyLimitExceeded, httperr.New(http.StatusTooManyRequests, "transfer_limit_exceeded", "daily transfer limit exceeded")},
+
+	{services.ErrPromoNotValid, httperr.New(http.StatusBadRequest, "promo_not_valid", "promo code params are not valid")},
+	{store.ErrPromoCodeAlreadyExists, httperr.New(http.StatusConflict, "promo_already_exists", "promo code already exists")},
+	{store.ErrPromoCodeNotFound, httperr.New(http.StatusNotFound, "promo_not_found", "promo code not found")},
+	{store.ErrPromoCodeNotActive, httperr.New(http.StatusUnprocessableEntity, "promo_not_active", "promo code is not active")},
+	{store.ErrPromoCodeExhausted, httperr.New(http.StatusConflict, "promo_exhausted", "promo code redemption limit reached")},
+	{store.ErrPromoCodeUserLimitReached, httperr.New(http.StatusConflict, "promo_user_limit_reached", "promo code user redemption limit reached")},
+
+	{services.ErrDisputeNotValid, httperr.New(http.StatusBadRequest, "dispute_not_valid", "dispute params are not valid")},
+	{store.ErrDisputeNotFound, httperr.New(http.StatusNotFound, "dispute_not_found", "dispute not found")},
+	{store.ErrDisputeOwnOrder, httperr.New(http.StatusConflict, "dispute_own_order", "order belongs to the claimant")},
+	{store.ErrDisputeAlreadyOpen, httperr.New(http.StatusConflict, "dispute_already_open", "dispute for the order is already open")},
+	{store.ErrDisputeAlreadyResolved, httperr.New(http.StatusConflict, "dispute_already_resolved", "dispute already resolved")},
+	{store.ErrDisputeWithdrawalOrder, httperr.New(http.StatusUnprocessableEntity, "dispute_withdrawal_order", "order is a withdrawal and cannot be disputed")},
+	{store.ErrDisputeInsufficientFunds, httperr.New(http.StatusConflict, "dispute_insufficient_funds", "owner balance does not cover the reversal")},
+	{store.ErrDisputeStale, httperr.New(http.StatusConflict, "dispute_stale", "order owner changed since the dispute was opened")},
+
+	{services.ErrWebhookDisabled, httperr.New(http.StatusNotFound, "webhook_disabled", "accrual webhook is not configured for partner")},
+	{services.ErrWebhookSignature, httperr.New(http.StatusUnauthorized, "webhook_signature_not_valid", "accrual webhook signature is not valid")},
+	{services.ErrWebhookPayload, httperr.New(http.StatusBadRequest, "webhook_payload_not_valid", "accrual webhook payload is not valid")},
+	{services.ErrWebhookForeignOrder, httperr.New(http.StatusForbidden, "webhook_order_not_owned", "order is not routed to the webhook partner")},
+}
+
+// apiError приводит ошибку к ответу API; неизвестные ошибки остаются как есть и отдаются как 500
+func apiError(err error) error {
+	var apiErr *httperr.Error
+	if errors.As(err, &apiErr) {
+		return err
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return errBodyTooLarge
+	}
+	for _, m := range errorMapping {
+		if errors.Is(err, m.err) {
+			return m.apiErr
+		}
+	}
+	return err
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	httperr.Write(w, r, apiError(err))
+}
@@ -0,0 +1,141 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/services"
+	"github.com/ShvetsovYura/oygophermart/internal/store"
+	"github.com/ShvetsovYura/oygophermart/internal/utils"
+	"github.com/ShvetsovYura/oygophermart/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) models.ErrorResp {
+	t.Helper()
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var resp models.ErrorResp
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp
+}
+
+func TestWriteErrorMapping(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("withdraw: %w", store.ErrInsufficientFundsInDB), http.StatusPaymentRequired, "insufficient_funds"},
+		{store.ErrOrdersNotFoundInDB, http.StatusNotFound, "order_not_found"},
+		{services.ErrOrderStatusTransition, http.StatusConflict, "order_status_conflict"},
+		{&http.MaxBytesError{Limit: 1}, http.StatusRequestEntityTooLarge, "body_too_large"},
+		{errQueryNotValid, http.StatusBadRequest, "query_not_valid"},
+		{errors.New("connection refused"), http.StatusInternalServerError, "internal_error"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		writeError(rec, httptest.NewRequest(http.MethodGet, "/", nil), tt.err)
+		assert.Equal(t, tt.status, rec.Code, tt.err.Error())
+		resp := decodeError(t, rec)
+		assert.Equal(t, tt.code, resp.Code)
+		assert.NotContains(t, resp.Message, "connection refused")
+	}
+}
+
+func TestUserWithdrawErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokens := mocks.NewMockTokener(ctrl)
+	tokens.EXPECT().ValidateSign("token").Return(true, nil).AnyTimes()
+	tokens.EXPECT().ExtractUserID("token").Return(uint64(1), nil).AnyTimes()
+	orders := mocks.NewMockOrderWorker(ctrl)
+
+	wa := NewHTTPRouter(orders, nil, tokens, nil, nil, utils.NewOrderNumberRegistry(), nil, nil, nil)
+	wa.InitRouter()
+
+	withdraw := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", bytes.NewBufferString(body))
+		req.Header.Set("X-Request-Id", "req-1")
+		req.AddCookie(&http.Cookie{Name: "token", Value: "token"})
+		rec := httptest.NewRecorder()
+		wa.GetRouter().ServeHTTP(rec, req)
+		return rec
+	}
+
+	orders.EXPECT().Withdraw(gomock.Any(), uint64(1), "2377225624", 1000.0).Return(services.ErrInsufficientFunds)
+	rec := withdraw(`{"order":"2377225624","sum":1000}`)
+	assert.Equal(t, http.StatusPaymentRequired, rec.Code)
+	assert.Equal(t, "req-1", rec.Header().Get("X-Request-Id"))
+	resp := decodeError(t, rec)
+	assert.Equal(t, models.ErrorResp{Code: "insufficient_funds", Message: "insufficient funds", RequestID: "req-1"}, resp)
+
+	orders.EXPECT().Withdraw(gomock.Any(), uint64(1), "2377225624", 10.0).Return(store.ErrOrderAlreadyExistsInDB)
+	rec = withdraw(`{"order":"2377225624","sum":10}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, "order_already_exists", decodeError(t, rec).Code)
+
+	rec = withdraw(`{"order":`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "body_not_valid", decodeError(t, rec).Code)
+
+	rec = withdraw(`{"order":"123","sum":10}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, "order_number_not_valid", decodeError(t, rec).Code)
+}
+
+func TestUserExportErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokens := mocks.NewMockTokener(ctrl)
+	tokens.EXPECT().ValidateSign("token").Return(true, nil).AnyTimes()
+	tokens.EXPECT().ExtractUserID("token").Return(uint64(1), nil).AnyTimes()
+	orders := mocks.NewMockOrderWorker(ctrl)
+
+	wa := NewHTTPRouter(orders, nil, tokens, nil, nil, utils.NewOrderNumberRegistry(), nil, nil, nil)
+	wa.InitRouter()
+
+	export := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/user/export/orders", nil)
+		req.AddCookie(&http.Cookie{Name: "token", Value: "token"})
+		rec := httptest.NewRecorder()
+		wa.GetRouter().ServeHTTP(rec, req)
+		return rec
+	}
+
+	// первая страница не прочитана - обычный ответ с ошибкой
+	orders.EXPECT().ExportUserData(gomock.Any(), uint64(1), "orders", "csv", gomock.Any()).Return(errors.New("connection refused"))
+	rec := export()
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, "internal_error", decodeError(t, rec).Code)
+
+	// часть файла уже отправлена - соединение обрывается
+	orders.EXPECT().ExportUserData(gomock.Any(), uint64(1), "orders", "csv", gomock.Any()).DoAndReturn(
+		func(ctx context.Context, userID uint64, kind string, format string, w io.Writer) error {
+			fmt.Fprintln(w, "number,status,accrual,uploaded_at,updated_at")
+			return errors.New("connection refused")
+		})
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { export() })
+
+	orders.EXPECT().ExportUserData(gomock.Any(), uint64(1), "orders", "csv", gomock.Any()).DoAndReturn(
+		func(ctx context.Context, userID uint64, kind string, format string, w io.Writer) error {
+			fmt.Fprintln(w, "number,status,accrual,uploaded_at,updated_at")
+			return nil
+		})
+	rec = export()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=orders.csv", rec.Header().Get("Content-Disposition"))
+}
@@ -0,0 +1,60 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type monitorStub struct {
+	health models.HealthResp
+}
+
+func (m monitorStub) LimiterStates() []models.LimiterStateResp { return nil }
+
+func (m monitorStub) Health() models.HealthResp { return m.health }
+
+type pingerStub struct {
+	err error
+}
+
+func (p pingerStub) Ping(ctx context.Context) error { return p.err }
+
+func TestHealth(t *testing.T) {
+	openBreaker := []models.BreakerStateResp{{Partner: "default", State: "open", Failures: 5}}
+	tests := []struct {
+		name    string
+		monitor models.HealthResp
+		dbErr   error
+		code    int
+		status  string
+		db      string
+	}{
+		{"all ok", models.HealthResp{Status: models.HealthOK}, nil, http.StatusOK, models.HealthOK, models.HealthOK},
+		{"partner breaker open", models.HealthResp{Status: models.HealthDegraded, Partners: openBreaker}, nil, http.StatusOK, models.HealthDegraded, models.HealthOK},
+		{"db down", models.HealthResp{Status: models.HealthOK}, errors.New("connection refused"), http.StatusServiceUnavailable, models.HealthUnavailable, models.HealthUnavailable},
+		{"db down and partner breaker open", models.HealthResp{Status: models.HealthDegraded, Partners: openBreaker}, errors.New("connection refused"), http.StatusServiceUnavailable, models.HealthUnavailable, models.HealthUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wa := NewHTTPRouter(nil, nil, nil, nil, nil, nil, monitorStub{health: tt.monitor}, nil, pingerStub{err: tt.dbErr})
+			wa.InitRouter()
+
+			rec := httptest.NewRecorder()
+			wa.GetRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+			assert.Equal(t, tt.code, rec.Code)
+			var resp models.HealthResp
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, tt.status, resp.Status)
+			assert.Equal(t, tt.db, resp.DB)
+			assert.Len(t, resp.Partners, len(tt.monitor.Partners))
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package router
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/utils"
+	"github.com/ShvetsovYura/oygophermart/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminCreatePromoRequiresAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokens := mocks.NewMockTokener(ctrl)
+	tokens.EXPECT().ValidateSign(gomock.Any()).Return(true, nil).AnyTimes()
+	tokens.EXPECT().ExtractUserID("admin").Return(uint64(1), nil).AnyTimes()
+	tokens.EXPECT().ExtractUserID("user").Return(uint64(2), nil).AnyTimes()
+	users := mocks.NewMockUserWorker(ctrl)
+	users.EXPECT().IsAdmin(gomock.Any(), uint64(1)).Return(true, nil).AnyTimes()
+	users.EXPECT().IsAdmin(gomock.Any(), uint64(2)).Return(false, nil).AnyTimes()
+	promos := mocks.NewMockPromoWorker(ctrl)
+
+	wa := NewHTTPRouter(nil, users, tokens, promos, nil, utils.NewOrderNumberRegistry(), nil, nil, nil)
+	wa.InitRouter()
+
+	create := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/promo", bytes.NewBufferString(`{"code":"SPRING","value":50}`))
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+		rec := httptest.NewRecorder()
+		wa.GetRouter().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := create("user")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, "admin_required", decodeError(t, rec).Code)
+
+	promos.EXPECT().CreatePromo(gomock.Any(), uint64(1), models.PromoReq{Code: "SPRING", Value: 50}).Return(nil)
+	rec = create("admin")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
@@ -6,29 +6,54 @@ import (
 	"errors"
 	"io"
 	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ShvetsovYura/oygophermart/internal/logger"
 	"github.com/ShvetsovYura/oygophermart/internal/middlewares"
 	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/options"
 	"github.com/ShvetsovYura/oygophermart/internal/services"
-	"github.com/ShvetsovYura/oygophermart/internal/store"
-	"github.com/ShvetsovYura/oygophermart/internal/utils"
 	"github.com/go-chi/chi/v5"
 )
 
+const (
+	maxImportSize  = 10 << 20
+	maxWebhookSize = 1 << 20
+
+	healthDBTimeout = 2 * time.Second
+)
+
+//go:generate mockgen -destination=../../mocks/mock_router.go -package=mocks github.com/ShvetsovYura/oygophermart/internal/router Tokener,OrderWorker,UserWorker,PromoWorker
 type OrderWorker interface {
 	CreateOrder(ctx context.Context, userID uint64, orderID string) error
+	CreateOrders(ctx context.Context, userID uint64, orderIDs []string) ([]models.BulkOrderResp, error)
 	GetUserBalance(ctx context.Context, userID uint64) models.BalanceModel
 	Withdraw(ctx context.Context, userID uint64, orderID string, value float64) error
 	UserWithdrawals(ctx context.Context, userID uint64) ([]models.OrderGroupedModel, error)
-	GetUserOrders(ctx context.Context, userID uint64) ([]models.OrderGroupedModel, error)
+	GetUserOrders(ctx context.Context, userID uint64, query models.OrderQuery) ([]models.OrderGroupedModel, string, error)
+	GetUserOrder(ctx context.Context, userID uint64, orderID string) (*models.OrderDetailResp, error)
+	CancelOrder(ctx context.Context, userID uint64, orderID string) error
+	Transfer(ctx context.Context, userID uint64, toLogin string, value float64) error
+	UserTransfers(ctx context.Context, userID uint64) ([]models.TransferResp, error)
+	UserHistory(ctx context.Context, userID uint64, query models.HistoryQuery) (*models.HistoryResp, error)
+	ExportUserData(ctx context.Context, userID uint64, kind string, format string, w io.Writer) error
+	ImportOrders(ctx context.Context, r io.Reader, dryRun bool) (*models.ImportResp, error)
 }
 
 type UserWorker interface {
-	CreateUser(ctx context.Context, login string, password string) (int64, error)
+	CreateUser(ctx context.Context, login string, password string, referral string, ip string) (int64, error)
 	Login(ctx context.Context, login string, password string) (int64, error)
+	IsAdmin(ctx context.Context, userID uint64) (bool, error)
+	GetReferralInfo(ctx context.Context, userID uint64) (*models.ReferralResp, error)
+}
+
+type PromoWorker interface {
+	CreatePromo(ctx context.Context, adminID uint64, req models.PromoReq) error
+	RedeemPromo(ctx context.Context, userID uint64, code string) (float64, error)
 }
 
 type Tokener interface {
@@ -37,18 +62,65 @@ type Tokener interface {
 	ExtractUserID(token string) (uint64, error)
 }
 
+type DisputeWorker interface {
+	OpenDispute(ctx context.Context, userID uint64, req models.DisputeReq) (int64, error)
+	UserDisputes(ctx context.Context, userID uint64) ([]models.DisputeResp, error)
+	Disputes(ctx context.Context, status string) ([]models.DisputeResp, error)
+	ResolveDispute(ctx context.Context, adminID uint64, disputeID int64, req models.DisputeResolveReq) error
+	UserNotifications(ctx context.Context, userID uint64) ([]models.NotificationResp, error)
+}
+
+type OrderValidator interface {
+	Validate(number string) bool
+}
+
+type WebhookWorker interface {
+	AccrualWebhook(ctx context.Context, partner string, signature string, body []byte) (int, error)
+}
+
+type AccrualMonitor interface {
+	LimiterStates() []models.LimiterStateResp
+	Health() models.HealthResp
+}
+
+type DBPinger interface {
+	Ping(ctx context.Context) error
+}
+
 type HTTPRouter struct {
-	orderService OrderWorker
-	userService  UserWorker
-	tokenService Tokener
-	rawRouter    *chi.Mux
+	orderService   OrderWorker
+	userService    UserWorker
+	tokenService   Tokener
+	promoService   PromoWorker
+	disputeService DisputeWorker
+	orderValidator OrderValidator
+	accrualMonitor AccrualMonitor
+	webhookService WebhookWorker
+	db             DBPinger
+	rawRouter      *chi.Mux
 }
 
-func NewHTTPRouter(orderService OrderWorker, userService UserWorker, tokenService Tokener) *HTTPRouter {
+func NewHTTPRouter(
+	orderService OrderWorker,
+	userService UserWorker,
+	tokenService Tokener,
+	promoService PromoWorker,
+	disputeService DisputeWorker,
+	orderValidator OrderValidator,
+	accrualMonitor AccrualMonitor,
+	webhookService WebhookWorker,
+	db DBPinger,
+) *HTTPRouter {
 	api := &HTTPRouter{
-		orderService: orderService,
-		userService:  userService,
-		tokenService: tokenService,
+		orderService:   orderService,
+		userService:    userService,
+		tokenService:   tokenService,
+		promoService:   promoService,
+		disputeService: disputeService,
+		orderValidator: orderValidator,
+		accrualMonitor: accrualMonitor,
+		webhookService: webhookService,
+		db:             db,
 	}
 	return api
 }
@@ -59,20 +131,49 @@ func (wa *HTTPRouter) GetRouter() *chi.Mux {
 
 func (wa *HTTPRouter) InitRouter() {
 	r := chi.NewRouter()
+	r.Use(middlewares.RequestID)
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, errNotFound)
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, errMethodNotAllowed)
+	})
 	ms := []func(http.Handler) http.Handler{
 		middlewares.CheckAuthCookie(wa.tokenService),
 		middlewares.ExtractUserID(wa.tokenService),
 	}
+	adminMs := append(ms, middlewares.CheckAdmin(wa.userService))
 
 	r.Route("/api", func(r chi.Router) {
+		r.Get("/health", wa.health)
+		r.Post("/integrations/accrual/webhook", wa.accrualWebhook)
 		r.Route("/user", func(r chi.Router) {
 			r.Post("/register", wa.userRegister)
 			r.Post("/login", wa.userLogin)
 			r.With(ms...).Post("/orders", wa.userLoadOrders)
+			r.With(ms...).Post("/orders/batch", wa.userLoadOrdersBatch)
 			r.With(ms...).Get("/orders", wa.userListOrders)
+			r.With(ms...).Get("/orders/{number}", wa.userGetOrder)
+			r.With(ms...).Delete("/orders/{number}", wa.userCancelOrder)
 			r.With(ms...).Get("/balance", wa.userBalance)
 			r.With(ms...).Post("/balance/withdraw", wa.userWithdraw)
+			r.With(ms...).Post("/balance/transfer", wa.userTransfer)
+			r.With(ms...).Get("/transfers", wa.userTransfers)
+			r.With(ms...).Get("/history", wa.userHistory)
+			r.With(ms...).Get("/export/{kind}", wa.userExport)
 			r.With(ms...).Get("/withdrawals", wa.userWithdrawals)
+			r.With(ms...).Post("/promo", wa.userRedeemPromo)
+			r.With(ms...).Get("/referral", wa.userReferral)
+			r.With(ms...).Post("/disputes", wa.userOpenDispute)
+			r.With(ms...).Get("/disputes", wa.userDisputes)
+			r.With(ms...).Get("/notifications", wa.userNotifications)
+		})
+		r.Route("/admin", func(r chi.Router) {
+			r.With(adminMs...).Post("/promo", wa.adminCreatePromo)
+			r.With(adminMs...).Post("/orders/import", wa.adminImportOrders)
+			r.With(adminMs...).Get("/disputes", wa.adminDisputes)
+			r.With(adminMs...).Post("/disputes/{id}/resolve", wa.adminResolveDispute)
+			r.With(adminMs...).Get("/accrual/limiters", wa.adminAccrualLimiters)
 		})
 	})
 	wa.rawRouter = r
@@ -83,7 +184,7 @@ func (wa *HTTPRouter) userRegister(w http.ResponseWriter, r *http.Request) {
 	var user models.UserReq
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	defer r.Body.Close()
@@ -91,22 +192,18 @@ func (wa *HTTPRouter) userRegister(w http.ResponseWriter, r *http.Request) {
 	err = json.Unmarshal(body, &user)
 
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errBodyNotValid)
 		return
 	}
 
-	id, err := wa.userService.CreateUser(r.Context(), user.Login, user.Password)
+	id, err := wa.userService.CreateUser(r.Context(), user.Login, user.Password, user.Referral, clientIP(r))
 	if err != nil {
-		if errors.Is(err, services.ErrUserAlreadyExists) {
-			w.WriteHeader(http.StatusConflict)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		writeError(w, r, err)
 		return
 	}
 	token, err := wa.tokenService.GenerateToken(uint64(id))
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	c := http.Cookie{Name: "token", Value: token, HttpOnly: true, MaxAge: 3600}
@@ -119,29 +216,25 @@ func (wa *HTTPRouter) userLogin(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	defer r.Body.Close()
 
 	err = json.Unmarshal(body, &user)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errBodyNotValid)
 		return
 	}
 
 	uid, err := wa.userService.Login(r.Context(), user.Login, user.Password)
 	if err != nil {
-		if errors.Is(err, services.ErrUserNotFound) || errors.Is(err, services.ErrNotValidLoginOrPassword) {
-			w.WriteHeader(http.StatusUnauthorized)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		writeError(w, r, err)
 		return
 	}
 	token, err := wa.tokenService.GenerateToken(uint64(uid))
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	c := http.Cookie{
@@ -159,60 +252,121 @@ func (wa *HTTPRouter) userLogin(w http.ResponseWriter, r *http.Request) {
 func (wa *HTTPRouter) userLoadOrders(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(models.UIDKey).(uint64)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		writeError(w, r, middlewares.ErrUnauthorized)
 		return
 	}
 
 	contentType := r.Header.Get("Content-Type")
 	if contentType != "text/plain" {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errContentTypeNotValid)
 		return
 	}
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
 	}
 	defer r.Body.Close()
 	orderID := string(body)
-	isValid, err := utils.CheckLuhnFromStr(orderID)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	if !isValid {
-		w.WriteHeader(http.StatusUnprocessableEntity)
+	if !wa.orderValidator.Validate(orderID) {
+		writeError(w, r, errOrderNumberNotValid)
 		return
 	}
 
 	err = wa.orderService.CreateOrder(r.Context(), userID, orderID)
 	if err != nil {
-		logger.Log.Debugf("Error on create order, %v", err)
+		logger.Log.Debugf("error on create order: %v", err)
 		if errors.Is(err, services.ErrOrderAlreadyAddedByUser) {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
-		if errors.Is(err, services.ErrOrderAlreadyAddedByAnotherUser) {
-			w.WriteHeader(http.StatusConflict)
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (wa *HTTPRouter) userLoadOrdersBatch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(models.UIDKey).(uint64)
+	if !ok {
+		writeError(w, r, middlewares.ErrUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer r.Body.Close()
+
+	var orderIDs []string
+	switch r.Header.Get("Content-Type") {
+	case "application/json":
+		err = json.Unmarshal(body, &orderIDs)
+		if err != nil {
+			writeError(w, r, errBodyNotValid)
 			return
 		}
-		w.WriteHeader(http.StatusBadRequest)
-		logger.Log.Debugf("error on create order: %v", err)
+	case "text/plain":
+		for _, line := range strings.Split(string(body), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				orderIDs = append(orderIDs, line)
+			}
+		}
+	default:
+		writeError(w, r, errContentTypeNotValid)
 		return
 	}
-	w.WriteHeader(http.StatusAccepted)
+
+	result, err := wa.orderService.CreateOrders(r.Context(), userID, orderIDs)
+	if err != nil {
+		logger.Log.Debugf("error on create orders batch: %v", err)
+		writeError(w, r, err)
+		return
+	}
+	resp, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(resp)
 }
 
 func (wa *HTTPRouter) userListOrders(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(models.UIDKey).(uint64)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		writeError(w, r, middlewares.ErrUnauthorized)
 		return
 	}
 
+	params := r.URL.Query()
+	query := models.OrderQuery{
+		Statuses: splitParam(params["status"]),
+		Cursor:   params.Get("cursor"),
+		Sort:     params.Get("sort"),
+	}
+	var err error
+	if query.From, err = parseTimeParam(params.Get("from")); err != nil {
+		writeError(w, r, errQueryNotValid.WithDetails(map[string]string{"param": "from"}))
+		return
+	}
+	if query.To, err = parseTimeParam(params.Get("to")); err != nil {
+		writeError(w, r, errQueryNotValid.WithDetails(map[string]string{"param": "to"}))
+		return
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			writeError(w, r, errQueryNotValid.WithDetails(map[string]string{"param": "limit"}))
+			return
+		}
+	}
+
 	w.Header().Add("Content-Type", "application/json")
-	orders, err := wa.orderService.GetUserOrders(r.Context(), userID)
+	orders, next, err := wa.orderService.GetUserOrders(r.Context(), userID, query)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	if len(orders) < 1 {
@@ -221,17 +375,69 @@ func (wa *HTTPRouter) userListOrders(w http.ResponseWriter, r *http.Request) {
 	}
 	response, err := json.Marshal(orders)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
 	w.Write(response)
 }
 
+func (wa *HTTPRouter) userGetOrder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(models.UIDKey).(uint64)
+	if !ok {
+		writeError(w, r, middlewares.ErrUnauthorized)
+		return
+	}
+
+	orderID := chi.URLParam(r, "number")
+	if !wa.orderValidator.Validate(orderID) {
+		writeError(w, r, errOrderNumberNotValid)
+		return
+	}
+
+	order, err := wa.orderService.GetUserOrder(r.Context(), userID, orderID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp, err := json.Marshal(order)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+func (wa *HTTPRouter) userCancelOrder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(models.UIDKey).(uint64)
+	if !ok {
+		writeError(w, r, middlewares.ErrUnauthorized)
+		return
+	}
+
+	orderID := chi.URLParam(r, "number")
+	if !wa.orderValidator.Validate(orderID) {
+		writeError(w, r, errOrderNumberNotValid)
+		return
+	}
+
+	err := wa.orderService.CancelOrder(r.Context(), userID, orderID)
+	if err != nil {
+		logger.Log.Debugf("error on cancel order: %v", err)
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (wa *HTTPRouter) userBalance(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(models.UIDKey).(uint64)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		writeError(w, r, middlewares.ErrUnauthorized)
 		return
 	}
 
@@ -245,55 +451,42 @@ func (wa *HTTPRouter) userBalance(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := json.Marshal(balanceResp)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
-	_, err = w.Write(resp)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
 }
 
 func (wa *HTTPRouter) userWithdraw(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(models.UIDKey).(uint64)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		writeError(w, r, middlewares.ErrUnauthorized)
 		return
 	}
 	var req models.WithdrawReq
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		logger.Log.Debugf("err: %e", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	defer r.Body.Close()
 	err = json.Unmarshal(body, &req)
 	if err != nil {
-		logger.Log.Debugf("err on withdraw: %e", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		logger.Log.Debugf("err on withdraw: %v", err)
+		writeError(w, r, errBodyNotValid)
 		return
 	}
 
 	logger.Log.Debugf("withdraw req: %v", req)
 
-	isValid, err := utils.CheckLuhnFromStr(req.OrderID)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	if !isValid {
-		w.WriteHeader(http.StatusUnprocessableEntity)
+	if !wa.orderValidator.Validate(req.OrderID) {
+		writeError(w, r, errOrderNumberNotValid)
 		return
 	}
 	err = wa.orderService.Withdraw(r.Context(), userID, req.OrderID, float64(req.Sum))
 	if err != nil {
-		if errors.Is(err, store.ErrOrderAlreadyExistsInDB) {
-			http.Error(w, "Order already exists", http.StatusUnprocessableEntity)
-		}
-		w.WriteHeader(http.StatusPaymentRequired)
+		writeError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -302,13 +495,13 @@ func (wa *HTTPRouter) userWithdraw(w http.ResponseWriter, r *http.Request) {
 func (wa *HTTPRouter) userWithdrawals(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(models.UIDKey).(uint64)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		writeError(w, r, middlewares.ErrUnauthorized)
 		return
 	}
 	w.Header().Add("Content-Type", "application/json")
 	orders, err := wa.orderService.UserWithdrawals(r.Context(), userID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	if len(orders) < 1 {
@@ -326,13 +519,487 @@ func (wa *HTTPRouter) userWithdrawals(w http.ResponseWriter, r *http.Request) {
 	}
 	resp, err := json.Marshal(respOrders)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
-	_, err = w.Write(resp)
+	w.Write(resp)
+}
+
+func (wa *HTTPRouter) userTransfer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(models.UIDKey).(uint64)
+	if !ok {
+		writeError(w, r, middlewares.ErrUnauthorized)
+		return
+	}
+	var req models.TransferReq
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+	defer r.Body.Close()
+	err = json.Unmarshal(body, &req)
+	if err != nil || req.Login == "" {
+		writeError(w, r, errBodyNotValid)
+		return
+	}
+
+	err = wa.orderService.Transfer(r.Context(), userID, req.Login, req.Sum)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
+
+func (wa *HTTPRouter) userTransfers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(models.UIDKey).(uint64)
+	if !ok {
+		writeError(w, r, middlewares.ErrUnauthorized)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	transfers, err := wa.orderService.UserTransfers(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if len(transfers) < 1 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	resp, err := json.Marshal(transfers)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Write(resp)
+}
+
+func (wa *HTTPRouter) userHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(models.UIDKey).(uint64)
+	if !ok {
+		writeError(w, r, middlewares.ErrUnauthorized)
+		return
+	}
+	params := r.URL.Query()
+	query := models.HistoryQuery{
+		Types:  splitParam(params["type"]),
+		Cursor: params.Get("cursor"),
+	}
+	var err error
+	if query.From, err = parseTimeParam(params.Get("from")); err != nil {
+		writeError(w, r, errQueryNotValid.WithDetails(map[string]string{"param": "from"}))
+		return
+	}
+	if query.To, err = parseTimeParam(params.Get("to")); err != nil {
+		writeError(w, r, errQueryNotValid.WithDetails(map[string]string{"param": "to"}))
+		return
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			writeError(w, r, errQueryNotValid.WithDetails(map[string]string{"param": "limit"}))
+			return
+		}
+	}
+
+	history, err := wa.orderService.UserHistory(r.Context(), userID, query)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp, err := json.Marshal(history)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+func (wa *HTTPRouter) userExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(models.UIDKey).(uint64)
+	if !ok {
+		writeError(w, r, middlewares.ErrUnauthorized)
+		return
+	}
+
+	kind := chi.URLParam(r, "kind")
+	format := r.URL.Query().Get("format")
+	contentType := "text/csv"
+	switch format {
+	case "", "csv":
+		format = "csv"
+	case "tsv":
+		contentType = "text/tab-separated-values"
+	default:
+		writeError(w, r, services.ErrExportFormatNotValid)
+		return
+	}
+	if kind != "orders" && kind != "withdrawals" && kind != "history" {
+		writeError(w, r, services.ErrExportKindNotValid)
+		return
+	}
+
+	ew := &exportWriter{ResponseWriter: w, header: func(h http.Header) {
+		h.Set("Content-Type", contentType)
+		h.Set("Content-Disposition", "attachment; filename="+kind+"."+format)
+	}}
+	err := wa.orderService.ExportUserData(r.Context(), userID, kind, format, ew)
+	if err == nil {
+		return
+	}
+	if !ew.started {
+		writeError(w, r, err)
+		return
+	}
+	// часть файла уже отправлена: обрываем соединение, чтобы клиент
+	// не принял обрезанную выгрузку за полную
+	logger.Log.Errorf("error on export %s: %v", kind, err)
+	panic(http.ErrAbortHandler)
+}
+
+// exportWriter выставляет заголовки выгрузки только при первой записи тела,
+// чтобы до нее ошибку можно было вернуть обычным ответом
+type exportWriter struct {
+	http.ResponseWriter
+	header  func(h http.Header)
+	started bool
+}
+
+func (w *exportWriter) Write(b []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.header(w.Header())
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (wa *HTTPRouter) adminImportOrders(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	defer r.Body.Close()
+
+	report, err := wa.orderService.ImportOrders(r.Context(), http.MaxBytesReader(w, r.Body, maxImportSize), dryRun)
+	if err != nil {
+		logger.Log.Debugf("error on import orders: %v", err)
+		writeError(w, r, err)
+		return
+	}
+	resp, err := json.Marshal(report)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+func (wa *HTTPRouter) userRedeemPromo(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(models.UIDKey).(uint64)
+	if !ok {
+		writeError(w, r, middlewares.ErrUnauthorized)
+		return
+	}
+	var req models.PromoRedeemReq
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer r.Body.Close()
+	err = json.Unmarshal(body, &req)
+	if err != nil || req.Code == "" {
+		writeError(w, r, errBodyNotValid)
+		return
+	}
+
+	value, err := wa.promoService.RedeemPromo(r.Context(), userID, req.Code)
+	if err != nil {
+		logger.Log.Debugf("error on redeem promo: %v", err)
+		writeError(w, r, err)
+		return
+	}
+
+	resp, err := json.Marshal(models.PromoRedeemResp{Code: req.Code, Value: value})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+func (wa *HTTPRouter) adminCreatePromo(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(models.UIDKey).(uint64)
+	if !ok {
+		writeError(w, r, middlewares.ErrUnauthorized)
+		return
+	}
+	var req models.PromoReq
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer r.Body.Close()
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		writeError(w, r, errBodyNotValid)
+		return
+	}
+
+	err = wa.promoService.CreatePromo(r.Context(), userID, req)
+	if err != nil {
+		logger.Log.Debugf("error on create promo: %v", err)
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (wa *HTTPRouter) userReferral(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(models.UIDKey).(uint64)
+	if !ok {
+		writeError(w, r, middlewares.ErrUnauthorized)
+		return
+	}
+	info, err := wa.userService.GetReferralInfo(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp, err := json.Marshal(info)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+func (wa *HTTPRouter) userOpenDispute(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(models.UIDKey).(uint64)
+	if !ok {
+		writeError(w, r, middlewares.ErrUnauthorized)
+		return
+	}
+	var req models.DisputeReq
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer r.Body.Close()
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		writeError(w, r, errBodyNotValid)
+		return
+	}
+	if !wa.orderValidator.Validate(req.OrderID) {
+		writeError(w, r, errOrderNumberNotValid)
+		return
+	}
+
+	id, err := wa.disputeService.OpenDispute(r.Context(), userID, req)
+	if err != nil {
+		logger.Log.Debugf("error on open dispute: %v", err)
+		writeError(w, r, err)
+		return
+	}
+	resp, err := json.Marshal(map[string]int64{"id": id})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(resp)
+}
+
+func (wa *HTTPRouter) userDisputes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(models.UIDKey).(uint64)
+	if !ok {
+		writeError(w, r, middlewares.ErrUnauthorized)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	disputes, err := wa.disputeService.UserDisputes(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if len(disputes) < 1 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	resp, err := json.Marshal(disputes)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Write(resp)
+}
+
+func (wa *HTTPRouter) userNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(models.UIDKey).(uint64)
+	if !ok {
+		writeError(w, r, middlewares.ErrUnauthorized)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	notifications, err := wa.disputeService.UserNotifications(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if len(notifications) < 1 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	resp, err := json.Marshal(notifications)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Write(resp)
+}
+
+func (wa *HTTPRouter) adminDisputes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	disputes, err := wa.disputeService.Disputes(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if len(disputes) < 1 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	resp, err := json.Marshal(disputes)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Write(resp)
+}
+
+func (wa *HTTPRouter) adminResolveDispute(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(models.UIDKey).(uint64)
+	if !ok {
+		writeError(w, r, middlewares.ErrUnauthorized)
+		return
+	}
+	disputeID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, r, errNotFound)
+		return
+	}
+	var req models.DisputeResolveReq
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer r.Body.Close()
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		writeError(w, r, errBodyNotValid)
+		return
+	}
+
+	err = wa.disputeService.ResolveDispute(r.Context(), userID, disputeID, req)
+	if err != nil {
+		logger.Log.Debugf("error on resolve dispute: %v", err)
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// splitParam принимает как повторяющиеся параметры (?type=a&type=b), так и список через запятую (?type=a,b)
+func splitParam(values []string) []string {
+	var result []string
+	for _, v := range values {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				result = append(result, p)
+			}
+		}
+	}
+	return result
+}
+
+func parseTimeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (wa *HTTPRouter) adminAccrualLimiters(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	resp, err := json.Marshal(wa.accrualMonitor.LimiterStates())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Write(resp)
+}
+
+// health отвечает 503 только когда недоступна собственная БД сервиса;
+// проблемы партнеров начислений - это degraded с кодом 200,
+// иначе балансировщик снимет все экземпляры из-за одного партнера
+func (wa *HTTPRouter) health(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	health := wa.accrualMonitor.Health()
+	health.DB = models.HealthOK
+	ctx, cancel := context.WithTimeout(r.Context(), healthDBTimeout)
+	defer cancel()
+	if err := wa.db.Ping(ctx); err != nil {
+		logger.Log.Errorf("health: db ping: %v", err)
+		health.DB = models.HealthUnavailable
+		health.Status = models.HealthUnavailable
+	}
+	resp, err := json.Marshal(health)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if health.Status == models.HealthUnavailable {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(resp)
+}
+
+func (wa *HTTPRouter) accrualWebhook(w http.ResponseWriter, r *http.Request) {
+	partner := r.Header.Get("X-Accrual-Partner")
+	if partner == "" {
+		partner = options.DefaultPartner
+	}
+	defer r.Body.Close()
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	n, err := wa.webhookService.AccrualWebhook(r.Context(), partner, r.Header.Get("X-Accrual-Signature"), body)
+	if err != nil {
+		logger.Log.Debugf("error on accrual webhook from %s: %v", partner, err)
+		writeError(w, r, err)
+		return
+	}
+	logger.Log.Debugf("accrual webhook from %s: %d records", partner, n)
+	w.WriteHeader(http.StatusAccepted)
+}
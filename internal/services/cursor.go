@@ -0,0 +1,46 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+)
+
+var ErrCursorNotValid = errors.New("cursor is not valid")
+
+func EncodeCursor(c models.Cursor) string {
+	raw := strconv.FormatInt(c.At.UnixNano(), 10) + ":" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(cursor string) (*models.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrCursorNotValid
+	}
+	ts, id, found := strings.Cut(string(raw), ":")
+	if !found || id == "" {
+		return nil, ErrCursorNotValid
+	}
+	nsec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, ErrCursorNotValid
+	}
+	return &models.Cursor{At: time.Unix(0, nsec), ID: id}, nil
+}
+
+// DecodeLedgerCursor разбирает курсор истории операций, где ID - числовой id записи журнала
+func DecodeLedgerCursor(cursor string) (*models.Cursor, error) {
+	c, err := DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = strconv.ParseInt(c.ID, 10, 64); err != nil {
+		return nil, ErrCursorNotValid
+	}
+	return c, nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	c := models.Cursor{
+		At: time.Date(2024, time.March, 10, 22, 30, 12, 123456789, time.UTC),
+		ID: "12345678903",
+	}
+
+	decoded, err := DecodeCursor(EncodeCursor(c))
+	assert.NoError(t, err)
+	assert.True(t, c.At.Equal(decoded.At))
+	assert.Equal(t, c.ID, decoded.ID)
+}
+
+func TestDecodeCursorNotValid(t *testing.T) {
+	tests := []string{"", "!!!", "MTIz", "YWJjOjE"}
+
+	for _, test := range tests {
+		_, err := DecodeCursor(test)
+		assert.ErrorIs(t, err, ErrCursorNotValid)
+	}
+}
+
+func TestDecodeLedgerCursor(t *testing.T) {
+	c := models.Cursor{At: time.Date(2024, time.March, 10, 22, 30, 12, 0, time.UTC), ID: "42"}
+	decoded, err := DecodeLedgerCursor(EncodeCursor(c))
+	assert.NoError(t, err)
+	assert.Equal(t, "42", decoded.ID)
+
+	// номер заказа допустим в курсоре заказов, но не в курсоре журнала
+	c.ID = "AB12"
+	_, err = DecodeCursor(EncodeCursor(c))
+	assert.NoError(t, err)
+	_, err = DecodeLedgerCursor(EncodeCursor(c))
+	assert.ErrorIs(t, err, ErrCursorNotValid)
+
+	_, err = DecodeLedgerCursor("!!!")
+	assert.ErrorIs(t, err, ErrCursorNotValid)
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+)
+
+var ErrDisputeNotValid = errors.New("dispute params are not valid")
+
+const maxEvidenceLen = 4000
+
+//go:generate mockgen -destination=../../mocks/mock_dispute.go -package=mocks github.com/ShvetsovYura/oygophermart/internal/services DisputeStorer,NotificationStorer
+type DisputeStorer interface {
+	AddDispute(ctx context.Context, orderID string, claimantID int64, evidence string) (int64, error)
+	GetDisputes(ctx context.Context, status string, userID *int64) ([]models.DisputeModel, error)
+	ResolveDispute(ctx context.Context, disputeID int64, adminID int64, reassign bool, comment string) error
+}
+
+type NotificationStorer interface {
+	GetUserNotifications(ctx context.Context, userID int64) ([]models.NotificationModel, error)
+}
+
+type DisputeService struct {
+	store              DisputeStorer
+	notificationsStore NotificationStorer
+}
+
+func NewDisputeService(store DisputeStorer, notificationsStore NotificationStorer) *DisputeService {
+	return &DisputeService{store: store, notificationsStore: notificationsStore}
+}
+
+func (s *DisputeService) OpenDispute(ctx context.Context, userID uint64, req models.DisputeReq) (int64, error) {
+	evidence := strings.TrimSpace(req.Evidence)
+	if evidence == "" || len(evidence) > maxEvidenceLen {
+		return 0, ErrDisputeNotValid
+	}
+	return s.store.AddDispute(ctx, req.OrderID, int64(userID), evidence)
+}
+
+func (s *DisputeService) UserDisputes(ctx context.Context, userID uint64) ([]models.DisputeResp, error) {
+	uid := int64(userID)
+	disputes, err := s.store.GetDisputes(ctx, "", &uid)
+	if err != nil {
+		return nil, err
+	}
+	var result = make([]models.DisputeResp, 0, len(disputes))
+	for _, d := range disputes {
+		result = append(result, models.DisputeResp{
+			ID:         d.ID,
+			OrderID:    d.OrderID,
+			Evidence:   d.Evidence,
+			Status:     d.Status,
+			Comment:    d.Comment,
+			CreatedAt:  d.CreatedAt,
+			ResolvedAt: d.ResolvedAt,
+		})
+	}
+	return result, nil
+}
+
+func (s *DisputeService) Disputes(ctx context.Context, status string) ([]models.DisputeResp, error) {
+	disputes, err := s.store.GetDisputes(ctx, strings.ToUpper(status), nil)
+	if err != nil {
+		return nil, err
+	}
+	var result = make([]models.DisputeResp, 0, len(disputes))
+	for _, d := range disputes {
+		result = append(result, models.DisputeResp{
+			ID:         d.ID,
+			OrderID:    d.OrderID,
+			ClaimantID: d.ClaimantID,
+			OwnerID:    d.OwnerID,
+			Evidence:   d.Evidence,
+			Status:     d.Status,
+			Comment:    d.Comment,
+			CreatedAt:  d.CreatedAt,
+			ResolvedAt: d.ResolvedAt,
+		})
+	}
+	return result, nil
+}
+
+func (s *DisputeService) ResolveDispute(ctx context.Context, adminID uint64, disputeID int64, req models.DisputeResolveReq) error {
+	var reassign bool
+	switch strings.ToLower(req.Decision) {
+	case "reassign":
+		reassign = true
+	case "reject":
+	default:
+		return ErrDisputeNotValid
+	}
+	return s.store.ResolveDispute(ctx, disputeID, int64(adminID), reassign, strings.TrimSpace(req.Comment))
+}
+
+func (s *DisputeService) UserNotifications(ctx context.Context, userID uint64) ([]models.NotificationResp, error) {
+	notifications, err := s.notificationsStore.GetUserNotifications(ctx, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+	var result = make([]models.NotificationResp, 0, len(notifications))
+	for _, n := range notifications {
+		result = append(result, models.NotificationResp{Message: n.Message, CreatedAt: n.CreatedAt})
+	}
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/store"
+	"github.com/ShvetsovYura/oygophermart/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenDispute(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockDisputeStorer(ctrl)
+	ctx := context.TODO()
+	s := NewDisputeService(m, nil)
+
+	tests := []struct {
+		name     string
+		req      models.DisputeReq
+		storeErr error
+		stored   bool
+		err      error
+	}{
+		{"empty evidence", models.DisputeReq{OrderID: "12345678903", Evidence: "  "}, nil, false, ErrDisputeNotValid},
+		{"too long evidence", models.DisputeReq{OrderID: "12345678903", Evidence: strings.Repeat("x", maxEvidenceLen+1)}, nil, false, ErrDisputeNotValid},
+		{"own order", models.DisputeReq{OrderID: "12345678903", Evidence: "receipt"}, store.ErrDisputeOwnOrder, true, store.ErrDisputeOwnOrder},
+		{"withdrawal order", models.DisputeReq{OrderID: "2377225624", Evidence: "receipt"}, store.ErrDisputeWithdrawalOrder, true, store.ErrDisputeWithdrawalOrder},
+		{"already open", models.DisputeReq{OrderID: "12345678903", Evidence: "receipt"}, store.ErrDisputeAlreadyOpen, true, store.ErrDisputeAlreadyOpen},
+		{"opened", models.DisputeReq{OrderID: "12345678903", Evidence: " receipt "}, nil, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.stored {
+				m.EXPECT().AddDispute(ctx, tt.req.OrderID, int64(2), "receipt").Return(int64(1), tt.storeErr)
+			}
+			id, err := s.OpenDispute(ctx, 2, tt.req)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), id)
+		})
+	}
+}
+
+func TestResolveDispute(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockDisputeStorer(ctrl)
+	ctx := context.TODO()
+	s := NewDisputeService(m, nil)
+
+	tests := []struct {
+		name     string
+		decision string
+		reassign bool
+		storeErr error
+		stored   bool
+		err      error
+	}{
+		{"reassign", "Reassign", true, nil, true, nil},
+		{"reject", "reject", false, nil, true, nil},
+		{"stale owner", "reassign", true, store.ErrDisputeStale, true, store.ErrDisputeStale},
+		{"withdrawal order", "reassign", true, store.ErrDisputeWithdrawalOrder, true, store.ErrDisputeWithdrawalOrder},
+		{"owner spent accrual", "reassign", true, store.ErrDisputeInsufficientFunds, true, store.ErrDisputeInsufficientFunds},
+		{"already resolved", "reject", false, store.ErrDisputeAlreadyResolved, true, store.ErrDisputeAlreadyResolved},
+		{"unknown decision", "approve", false, nil, false, ErrDisputeNotValid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.stored {
+				m.EXPECT().ResolveDispute(ctx, int64(5), int64(1), tt.reassign, "checked").Return(tt.storeErr)
+			}
+			err := s.ResolveDispute(ctx, 1, 5, models.DisputeResolveReq{Decision: tt.decision, Comment: " checked "})
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+)
+
+var ErrExportKindNotValid = errors.New("export kind is not valid")
+var ErrExportFormatNotValid = errors.New("export format is not valid")
+var ErrImportNotValid = errors.New("import file is not valid")
+
+const exportPageSize = 500
+
+var importHeader = []string{"login", "number", "status", "accrual", "uploaded_at"}
+
+func newCSVWriter(w io.Writer, format string) (*csv.Writer, error) {
+	cw := csv.NewWriter(w)
+	switch format {
+	case "", "csv":
+	case "tsv":
+		cw.Comma = '\t'
+	default:
+		return nil, ErrExportFormatNotValid
+	}
+	return cw, nil
+}
+
+func formatAccrual(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+// ExportUserData пишет выгрузку постранично, чтобы не держать все записи пользователя в памяти.
+// Первая страница читается до записи в w, поэтому ошибка чтения первой страницы
+// возвращается, когда в ответ еще ничего не отправлено.
+func (s *OrderService) ExportUserData(ctx context.Context, userID uint64, kind string, format string, w io.Writer) error {
+	cw, err := newCSVWriter(w, format)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case "orders":
+		err = s.exportOrders(ctx, userID, cw)
+	case "withdrawals":
+		err = s.exportHistory(ctx, userID, []string{"WITHDRAWAL"}, cw)
+	case "history":
+		err = s.exportHistory(ctx, userID, nil, cw)
+	default:
+		return ErrExportKindNotValid
+	}
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (s *OrderService) exportOrders(ctx context.Context, userID uint64, cw *csv.Writer) error {
+	filter := models.OrderFilter{Limit: exportPageSize}
+	for {
+		records, err := s.stores.orderStore.GetUserOrdersPage(ctx, userID, filter)
+		if err != nil {
+			return err
+		}
+		if filter.After == nil {
+			err = cw.Write([]string{"number", "status", "accrual", "uploaded_at", "updated_at"})
+			if err != nil {
+				return err
+			}
+		}
+		for _, r := range records {
+			err = cw.Write([]string{
+				r.ID,
+				r.Status,
+				formatAccrual(r.Accrual),
+				r.CreatedAt.Format(time.RFC3339),
+				r.UpdatedAt.Format(time.RFC3339),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		if len(records) < exportPageSize {
+			return nil
+		}
+		last := records[len(records)-1]
+		filter.After = &models.Cursor{At: last.CreatedAt, ID: last.ID}
+	}
+}
+
+func (s *OrderService) exportHistory(ctx context.Context, userID uint64, kinds []string, cw *csv.Writer) error {
+	filter := models.HistoryFilter{Kinds: kinds, Limit: exportPageSize}
+	for {
+		records, err := s.stores.orderStore.GetUserHistory(ctx, int64(userID), filter)
+		if err != nil {
+			return err
+		}
+		if filter.After == nil {
+			err = cw.Write([]string{"type", "order", "sum", "balance", "processed_at"})
+			if err != nil {
+				return err
+			}
+		}
+		for _, r := range records {
+			var orderID string
+			if r.OrderID != nil {
+				orderID = *r.OrderID
+			}
+			err = cw.Write([]string{
+				strings.ToLower(r.Kind),
+				orderID,
+				strconv.FormatFloat(r.Value, 'f', -1, 64),
+				strconv.FormatFloat(r.Balance, 'f', -1, 64),
+				r.CreatedAt.Format(time.RFC3339),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		if len(records) < exportPageSize {
+			return nil
+		}
+		last := records[len(records)-1]
+		filter.After = &models.Cursor{At: last.CreatedAt, ID: strconv.FormatInt(last.ID, 10)}
+	}
+}
+
+// ImportOrders загружает заказы из CSV со столбцами login,number,status,accrual,uploaded_at.
+// Строки с ошибками пропускаются и попадают в отчет, при dryRun ничего не сохраняется.
+func (s *OrderService) ImportOrders(ctx context.Context, r io.Reader, dryRun bool) (*models.ImportResp, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(importHeader)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, ErrImportNotValid
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	if !slices.Equal(header, importHeader) {
+		return nil, ErrImportNotValid
+	}
+
+	report := &models.ImportResp{DryRun: dryRun, Errors: make([]models.ImportErrorResp, 0)}
+	var rows []models.ImportOrderModel
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+		}
+		report.Total++
+		if err != nil {
+			report.Errors = append(report.Errors, models.ImportErrorResp{Line: line, Error: err.Error()})
+			continue
+		}
+		row, err := parseImportRow(line, rec, s.validator)
+		if err != nil {
+			report.Errors = append(report.Errors, models.ImportErrorResp{Line: line, OrderID: rec[1], Error: err.Error()})
+			continue
+		}
+		rows = append(rows, *row)
+	}
+
+	if len(rows) > 0 {
+		rowErrors, err := s.stores.orderStore.ImportOrders(ctx, rows, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range rowErrors {
+			report.Errors = append(report.Errors, models.ImportErrorResp{Line: e.Line, OrderID: e.OrderID, Error: e.Error})
+		}
+		report.Imported = len(rows) - len(rowErrors)
+	}
+	slices.SortFunc(report.Errors, func(a, b models.ImportErrorResp) int { return a.Line - b.Line })
+	return report, nil
+}
+
+func parseImportRow(line int, rec []string, validator OrderValidator) (*models.ImportOrderModel, error) {
+	row := &models.ImportOrderModel{
+		Line:    line,
+		Login:   strings.TrimSpace(rec[0]),
+		OrderID: strings.TrimSpace(rec[1]),
+		Status:  strings.ToUpper(strings.TrimSpace(rec[2])),
+	}
+	if row.Login == "" {
+		return nil, errors.New("login is empty")
+	}
+	if !validator.Validate(row.OrderID) {
+		return nil, errors.New("order number is not valid")
+	}
+	if !slices.Contains(importStatuses, models.OrderStatus(row.Status)) {
+		return nil, errors.New("status is not valid")
+	}
+	if accrual := strings.TrimSpace(rec[3]); accrual != "" {
+		v, err := strconv.ParseFloat(accrual, 64)
+		if err != nil || v < 0 {
+			return nil, errors.New("accrual is not valid")
+		}
+		if row.Status != "PROCESSED" {
+			return nil, errors.New("accrual is allowed only for PROCESSED orders")
+		}
+		row.Accrual = &v
+	}
+	if uploadedAt := strings.TrimSpace(rec[4]); uploadedAt != "" {
+		t, err := time.Parse(time.RFC3339, uploadedAt)
+		if err != nil {
+			return nil, errors.New("uploaded_at is not valid")
+		}
+		row.UploadedAt = &t
+	}
+	return row, nil
+}
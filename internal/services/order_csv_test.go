@@ -0,0 +1,75 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/utils"
+	"github.com/ShvetsovYura/oygophermart/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImportRow(t *testing.T) {
+	tests := []struct {
+		input    []string
+		expected bool
+	}{
+		{
+			input:    []string{"pipa", "12345678903", "processed", "100.5", "2024-03-10T22:30:12+03:00"},
+			expected: true,
+		}, {
+			input:    []string{"pipa", "12345678903", "NEW", "", ""},
+			expected: true,
+		}, {
+			input:    []string{"", "12345678903", "NEW", "", ""},
+			expected: false,
+		}, {
+			input:    []string{"pipa", "12345678900", "NEW", "", ""},
+			expected: false,
+		}, {
+			input:    []string{"pipa", "12345678903", "UNKNOWN", "", ""},
+			expected: false,
+		}, {
+			input:    []string{"pipa", "12345678903", "NEW", "100", ""},
+			expected: false,
+		}, {
+			input:    []string{"pipa", "12345678903", "PROCESSED", "-1", ""},
+			expected: false,
+		}, {
+			input:    []string{"pipa", "12345678903", "PROCESSED", "1", "10.03.2024"},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		row, err := parseImportRow(2, test.input, utils.NewOrderNumberRegistry())
+		if test.expected {
+			assert.NoError(t, err)
+			assert.Equal(t, test.input[1], row.OrderID)
+		} else {
+			assert.Error(t, err)
+		}
+	}
+}
+
+func TestExportUserDataFirstPageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockOrderStorer(ctrl)
+	ctx := context.TODO()
+	s := NewOrderService(m, nil, models.TransferLimits{}, utils.NewOrderNumberRegistry())
+
+	m.EXPECT().GetUserHistory(ctx, int64(1), gomock.Any()).Return(nil, errors.New("connection refused"))
+	var buf bytes.Buffer
+	assert.Error(t, s.ExportUserData(ctx, 1, "history", "csv", &buf))
+	assert.Empty(t, buf.String())
+
+	m.EXPECT().GetUserHistory(ctx, int64(1), gomock.Any()).Return(nil, nil)
+	assert.NoError(t, s.ExportUserData(ctx, 1, "history", "csv", &buf))
+	assert.Equal(t, "type,order,sum,balance,processed_at\n", buf.String())
+}
@@ -3,6 +3,9 @@ package services
 import (
 	"context"
 	"errors"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/ShvetsovYura/oygophermart/internal/logger"
 	"github.com/ShvetsovYura/oygophermart/internal/models"
@@ -11,14 +14,62 @@ import (
 var ErrOrderAlreadyAddedByUser = errors.New("the order has already been added by the user")
 var ErrOrderAlreadyAddedByAnotherUser = errors.New("the order has already been added by another user")
 var ErrInsufficientFunds = errors.New("insufficient funds")
+var ErrTransferRecipientNotFound = errors.New("transfer recipient not found")
+var ErrTransferToSelf = errors.New("transfer to self is not allowed")
+var ErrTransferNotValid = errors.New("transfer sum must be positive")
+var ErrFilterNotValid = errors.New("filter params are not valid")
+var ErrBulkOrdersEmpty = errors.New("no orders to upload")
+var ErrBulkOrdersTooMany = errors.New("too many orders to upload")
+var ErrOrderStatusTransition = errors.New("order status transition is not allowed")
 
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+	maxBulkOrders    = 1000
+)
+
+// статусы для фильтра списка заказов; отмененные заказы в список не попадают
+var orderStatuses = []models.OrderStatus{
+	models.OrderNew, models.OrderProcessing, models.OrderInvalid, models.OrderProcessed, models.OrderUnknown,
+}
+
+// статусы, с которыми заказ можно импортировать; UNKNOWN выставляет только агент начислений
+var importStatuses = []models.OrderStatus{
+	models.OrderNew, models.OrderProcessing, models.OrderInvalid, models.OrderProcessed,
+}
+
+// типы движений по балансу в истории и соответствующие им виды записей loyalty
+var historyKinds = map[string]string{
+	"accrual":      "ACCRUAL",
+	"withdrawal":   "WITHDRAWAL",
+	"promo":        "PROMO",
+	"referral":     "REFERRAL",
+	"transfer_in":  "TRANSFER_IN",
+	"transfer_out": "TRANSFER_OUT",
+	"adjustment":   "ADJUSTMENT",
+	"reversal":     "REVERSAL",
+}
+
+//go:generate mockgen -destination=../../mocks/mock_store.go -package=mocks github.com/ShvetsovYura/oygophermart/internal/services OrderStorer,UserStorer
 type OrderStorer interface {
 	GetUserOrders(ctx context.Context, userID uint64) ([]models.OrderGroupedModel, error)
+	GetUserOrdersPage(ctx context.Context, userID uint64, filter models.OrderFilter) ([]models.OrderGroupedModel, error)
 	GetOrdersByID(ctx context.Context, orderID string) ([]models.OrderModel, error)
 	AddNewOrder(ctx context.Context, userID int64, orderID string) error
-	GetUserOrderByID(ctx context.Context, orderID string, userID int64) (*models.LoyaltyOrderModel, error)
+	AddNewOrders(ctx context.Context, userID int64, orderIDs []string) (map[string]string, error)
+	ImportOrders(ctx context.Context, rows []models.ImportOrderModel, dryRun bool) ([]models.ImportErrorModel, error)
+	GetUserOrderByID(ctx context.Context, orderID string, userID int64) (*models.OrderDetailModel, error)
+	GetOrderPolls(ctx context.Context, orderID string) ([]models.OrderPollModel, error)
+	CancelOrder(ctx context.Context, orderID string, userID int64) error
 	GetUserBalance(ctx context.Context, userID uint64) models.BalanceModel
 	Withdraw(ctx context.Context, orderID string, userID int64, value float64) error
+	Transfer(ctx context.Context, fromUserID int64, toUserID int64, value float64, limits models.TransferLimits) error
+	GetUserTransfers(ctx context.Context, userID int64) ([]models.TransferModel, error)
+	GetUserHistory(ctx context.Context, userID int64, filter models.HistoryFilter) ([]models.HistoryRecordModel, error)
+}
+
+type OrderValidator interface {
+	Validate(number string) bool
 }
 
 type stores struct {
@@ -27,16 +78,18 @@ type stores struct {
 }
 
 type OrderService struct {
-	stores stores
+	stores         stores
+	transferLimits models.TransferLimits
+	validator      OrderValidator
 }
 
-func NewOrderService(orderStore OrderStorer, userStore UserStorer) *OrderService {
+func NewOrderService(orderStore OrderStorer, userStore UserStorer, transferLimits models.TransferLimits, validator OrderValidator) *OrderService {
 	s := stores{
 
 		orderStore: orderStore,
 		userStore:  userStore,
 	}
-	service := &OrderService{stores: s}
+	service := &OrderService{stores: s, transferLimits: transferLimits, validator: validator}
 	return service
 }
 
@@ -62,23 +115,127 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID uint64, orderID s
 
 }
 
-func (s *OrderService) GetUserOrders(ctx context.Context, userID uint64) ([]models.OrderGroupedModel, error) {
-	records, err := s.stores.orderStore.GetUserOrders(ctx, userID)
-	var result = make([]models.OrderGroupedModel, 0, len(records))
+func (s *OrderService) CreateOrders(ctx context.Context, userID uint64, orderIDs []string) ([]models.BulkOrderResp, error) {
+	if len(orderIDs) == 0 {
+		return nil, ErrBulkOrdersEmpty
+	}
+	if len(orderIDs) > maxBulkOrders {
+		return nil, ErrBulkOrdersTooMany
+	}
+
+	var valid = make([]string, 0, len(orderIDs))
+	for _, orderID := range orderIDs {
+		if s.validator.Validate(orderID) {
+			valid = append(valid, orderID)
+		}
+	}
+
+	var saved map[string]string
+	if len(valid) > 0 {
+		var err error
+		saved, err = s.stores.orderStore.AddNewOrders(ctx, int64(userID), valid)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var result = make([]models.BulkOrderResp, 0, len(orderIDs))
+	seen := make(map[string]bool, len(orderIDs))
+	for _, orderID := range orderIDs {
+		status, ok := saved[orderID]
+		switch {
+		case !ok:
+			status = models.BulkOrderInvalid
+		case seen[orderID]:
+			status = models.BulkOrderDuplicateOwn
+		}
+		seen[orderID] = true
+		result = append(result, models.BulkOrderResp{OrderID: orderID, Result: status})
+	}
+	return result, nil
+}
+
+func (s *OrderService) GetUserOrders(ctx context.Context, userID uint64, query models.OrderQuery) ([]models.OrderGroupedModel, string, error) {
+	limit, err := pageLimit(query.Limit)
+	if err != nil {
+		return nil, "", err
+	}
+	filter := models.OrderFilter{
+		From:  query.From,
+		To:    query.To,
+		Limit: limit + 1,
+	}
+	switch strings.ToLower(query.Sort) {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return nil, "", ErrFilterNotValid
+	}
+	for _, st := range query.Statuses {
+		st = strings.ToUpper(st)
+		if !slices.Contains(orderStatuses, models.OrderStatus(st)) {
+			return nil, "", ErrFilterNotValid
+		}
+		filter.Statuses = append(filter.Statuses, st)
+	}
+	if query.Cursor != "" {
+		filter.After, err = DecodeCursor(query.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+	}
 
+	records, err := s.stores.orderStore.GetUserOrdersPage(ctx, userID, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	var next string
+	if len(records) > limit {
+		records = records[:limit]
+		last := records[len(records)-1]
+		next = EncodeCursor(models.Cursor{At: last.CreatedAt, ID: last.ID})
+	}
+	return records, next, nil
+}
+
+func (s *OrderService) GetUserOrder(ctx context.Context, userID uint64, orderID string) (*models.OrderDetailResp, error) {
+	order, err := s.stores.orderStore.GetUserOrderByID(ctx, orderID, int64(userID))
 	if err != nil {
 		return nil, err
 	}
-	for _, r := range records {
-		if r.Accrual == nil {
-			result = append(result, r)
-		} else if *r.Accrual >= 0.0 {
-			result = append(result, r)
-		}
+	polls, err := s.stores.orderStore.GetOrderPolls(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.OrderDetailResp{
+		OrderID:    order.ID,
+		Status:     order.Status,
+		Accrual:    order.Accrual,
+		UploadedAt: order.CreatedAt,
+		UpdatedAt:  order.UpdatedAt,
+		Polls:      make([]models.OrderPollResp, 0, len(polls)),
+	}
+	for _, p := range polls {
+		result.Polls = append(result.Polls, models.OrderPollResp{
+			Status:   p.Status,
+			Accrual:  p.Accrual,
+			PolledAt: p.PolledAt,
+		})
 	}
 	return result, nil
 }
 
+func (s *OrderService) CancelOrder(ctx context.Context, userID uint64, orderID string) error {
+	order, err := s.stores.orderStore.GetUserOrderByID(ctx, orderID, int64(userID))
+	if err == nil && !models.OrderStatus(order.Status).CanTransitionTo(models.OrderCancelled) {
+		return ErrOrderStatusTransition
+	}
+	// если заказ не прочитался, причину отказа определит хранилище
+	return s.stores.orderStore.CancelOrder(ctx, orderID, int64(userID))
+}
+
 func (s *OrderService) GetUserBalance(ctx context.Context, userID uint64) models.BalanceModel {
 	record := s.stores.orderStore.GetUserBalance(ctx, userID)
 	return record
@@ -121,3 +278,109 @@ func (s *OrderService) UserWithdrawals(ctx context.Context, userID uint64) ([]mo
 
 	return result, nil
 }
+
+func (s *OrderService) Transfer(ctx context.Context, userID uint64, toLogin string, value float64) error {
+	if value <= 0 {
+		return ErrTransferNotValid
+	}
+	recipient, err := s.stores.userStore.GetUserByLogin(ctx, toLogin)
+	if err != nil {
+		return err
+	}
+	if recipient == nil {
+		return ErrTransferRecipientNotFound
+	}
+	if recipient.ID == int64(userID) {
+		return ErrTransferToSelf
+	}
+
+	err = s.stores.orderStore.Transfer(ctx, int64(userID), recipient.ID, value, s.transferLimits)
+	if err != nil {
+		logger.Log.Debugf("err on transfer %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s *OrderService) UserTransfers(ctx context.Context, userID uint64) ([]models.TransferResp, error) {
+	transfers, err := s.stores.orderStore.GetUserTransfers(ctx, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+	var result = make([]models.TransferResp, 0, len(transfers))
+	for _, t := range transfers {
+		if t.FromUserID == int64(userID) {
+			result = append(result, models.TransferResp{
+				Direction:   "OUT",
+				Login:       t.ToLogin,
+				Sum:         t.Value,
+				ProcessedAt: t.CreatedAt,
+			})
+		} else {
+			result = append(result, models.TransferResp{
+				Direction:   "IN",
+				Login:       t.FromLogin,
+				Sum:         t.Value,
+				ProcessedAt: t.CreatedAt,
+			})
+		}
+	}
+	return result, nil
+}
+
+func pageLimit(limit int) (int, error) {
+	if limit == 0 {
+		return defaultPageLimit, nil
+	}
+	if limit < 0 || limit > maxPageLimit {
+		return 0, ErrFilterNotValid
+	}
+	return limit, nil
+}
+
+func (s *OrderService) UserHistory(ctx context.Context, userID uint64, query models.HistoryQuery) (*models.HistoryResp, error) {
+	limit, err := pageLimit(query.Limit)
+	if err != nil {
+		return nil, err
+	}
+	filter := models.HistoryFilter{
+		From:  query.From,
+		To:    query.To,
+		Limit: limit + 1,
+	}
+	for _, t := range query.Types {
+		kind, ok := historyKinds[t]
+		if !ok {
+			return nil, ErrFilterNotValid
+		}
+		filter.Kinds = append(filter.Kinds, kind)
+	}
+	if query.Cursor != "" {
+		filter.After, err = DecodeLedgerCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	records, err := s.stores.orderStore.GetUserHistory(ctx, int64(userID), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.HistoryResp{Items: make([]models.HistoryRecordResp, 0, len(records))}
+	if len(records) > limit {
+		records = records[:limit]
+		last := records[len(records)-1]
+		result.NextCursor = EncodeCursor(models.Cursor{At: last.CreatedAt, ID: strconv.FormatInt(last.ID, 10)})
+	}
+	for _, r := range records {
+		result.Items = append(result.Items, models.HistoryRecordResp{
+			Type:        strings.ToLower(r.Kind),
+			OrderID:     r.OrderID,
+			Sum:         r.Value,
+			Balance:     r.Balance,
+			ProcessedAt: r.CreatedAt,
+		})
+	}
+	return result, nil
+}
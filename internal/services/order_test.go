@@ -1,41 +1,83 @@
 package services
 
-// import (
-// 	"context"
-// 	"testing"
-// 	"time"
-
-// 	"github.com/ShvetsovYura/oygophermart/internal/models"
-// 	"github.com/ShvetsovYura/oygophermart/mocks"
-// 	"github.com/golang/mock/gomock"
-// 	"github.com/stretchr/testify/assert"
-// )
-
-// func TestCreateOrder(t *testing.T) {
-// 	ctrl := gomock.NewController(t)
-// 	defer ctrl.Finish()
-
-// 	m := mocks.NewMockOrderStorer(ctrl)
-// 	ctx := context.TODO()
-// 	userOrders := []models.LoyaltyOrderModel{
-// 		{
-// 			Id:        1,
-// 			OrderId:   "123456",
-// 			Status:    "NEW",
-// 			Value:     123,
-// 			Type:      "ADD",
-// 			UserId:    321,
-// 			CreatedAt: time.Now(),
-// 			UpdatedAt: time.Now(),
-// 		},
-// 	}
-// 	m.EXPECT().GetUserOrders(ctx, "pipa").Return(userOrders, nil)
-
-// 	m.EXPECT().GetOrdersById(ctx, "123456").Return(userOrders, nil)
-// 	s := NewOrderService(m)
-// 	err := s.CreateOrder(ctx, "pipa", "123456")
-
-// 	if assert.Error(t, err) {
-// 		assert.Equal(t, ErrOrderAlreadyAddedByUser, err)
-// 	}
-// }
+import (
+	"context"
+	"testing"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/utils"
+	"github.com/ShvetsovYura/oygophermart/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockOrderStorer(ctrl)
+	ctx := context.TODO()
+	s := NewOrderService(m, nil, models.TransferLimits{}, utils.NewOrderNumberRegistry())
+
+	m.EXPECT().GetOrdersByID(ctx, "12345678903").Return([]models.OrderModel{{ID: "12345678903", UserID: 1}}, nil).Times(2)
+	assert.ErrorIs(t, s.CreateOrder(ctx, 1, "12345678903"), ErrOrderAlreadyAddedByUser)
+	assert.ErrorIs(t, s.CreateOrder(ctx, 2, "12345678903"), ErrOrderAlreadyAddedByAnotherUser)
+
+	m.EXPECT().GetOrdersByID(ctx, "2377225624").Return(nil, nil)
+	m.EXPECT().AddNewOrder(ctx, int64(1), "2377225624").Return(nil)
+	assert.NoError(t, s.CreateOrder(ctx, 1, "2377225624"))
+}
+
+func TestCancelOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockOrderStorer(ctrl)
+	ctx := context.TODO()
+	s := NewOrderService(m, nil, models.TransferLimits{}, utils.NewOrderNumberRegistry())
+
+	m.EXPECT().GetUserOrderByID(ctx, "12345678903", int64(1)).Return(&models.OrderDetailModel{ID: "12345678903", Status: "PROCESSED"}, nil)
+	assert.ErrorIs(t, s.CancelOrder(ctx, 1, "12345678903"), ErrOrderStatusTransition)
+
+	m.EXPECT().GetUserOrderByID(ctx, "2377225624", int64(1)).Return(&models.OrderDetailModel{ID: "2377225624", Status: "NEW"}, nil)
+	m.EXPECT().CancelOrder(ctx, "2377225624", int64(1)).Return(nil)
+	assert.NoError(t, s.CancelOrder(ctx, 1, "2377225624"))
+}
+
+func TestWithdraw(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockOrderStorer(ctrl)
+	ctx := context.TODO()
+	s := NewOrderService(m, nil, models.TransferLimits{}, utils.NewOrderNumberRegistry())
+
+	m.EXPECT().GetUserBalance(ctx, uint64(1)).Return(models.BalanceModel{Balance: 100}).Times(2)
+	assert.ErrorIs(t, s.Withdraw(ctx, 1, "2377225624", 200), ErrInsufficientFunds)
+
+	m.EXPECT().Withdraw(ctx, "2377225624", int64(1), 50.0).Return(nil)
+	assert.NoError(t, s.Withdraw(ctx, 1, "2377225624", 50))
+}
+
+func TestTransfer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockOrderStorer(ctrl)
+	u := mocks.NewMockUserStorer(ctrl)
+	ctx := context.TODO()
+	limits := models.TransferLimits{}
+	s := NewOrderService(m, u, limits, utils.NewOrderNumberRegistry())
+
+	assert.ErrorIs(t, s.Transfer(ctx, 1, "popa", 0), ErrTransferNotValid)
+
+	u.EXPECT().GetUserByLogin(ctx, "nobody").Return(nil, nil)
+	assert.ErrorIs(t, s.Transfer(ctx, 1, "nobody", 10), ErrTransferRecipientNotFound)
+
+	u.EXPECT().GetUserByLogin(ctx, "pipa").Return(&models.UserModel{ID: 1}, nil)
+	assert.ErrorIs(t, s.Transfer(ctx, 1, "pipa", 10), ErrTransferToSelf)
+
+	u.EXPECT().GetUserByLogin(ctx, "popa").Return(&models.UserModel{ID: 2}, nil)
+	m.EXPECT().Transfer(ctx, int64(1), int64(2), 10.0, limits).Return(nil)
+	assert.NoError(t, s.Transfer(ctx, 1, "popa", 10))
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+)
+
+var ErrPromoNotValid = errors.New("promo code params are not valid")
+
+//go:generate mockgen -destination=../../mocks/mock_promo.go -package=mocks github.com/ShvetsovYura/oygophermart/internal/services PromoStorer
+type PromoStorer interface {
+	AddPromoCode(ctx context.Context, promo models.PromoCodeModel) error
+	RedeemPromoCode(ctx context.Context, code string, userID int64, now time.Time) (*models.PromoCodeModel, error)
+}
+
+type PromoService struct {
+	store PromoStorer
+}
+
+func NewPromoService(store PromoStorer) *PromoService {
+	return &PromoService{store: store}
+}
+
+func (s *PromoService) CreatePromo(ctx context.Context, adminID uint64, req models.PromoReq) error {
+	promo := models.PromoCodeModel{
+		Code:           strings.TrimSpace(req.Code),
+		Value:          req.Value,
+		ValidFrom:      time.Now(),
+		ValidTo:        req.ValidTo,
+		MaxRedemptions: req.MaxRedemptions,
+		PerUserLimit:   req.PerUserLimit,
+		CreatedBy:      int64(adminID),
+	}
+	if req.ValidFrom != nil {
+		promo.ValidFrom = *req.ValidFrom
+	}
+	if promo.PerUserLimit == 0 {
+		promo.PerUserLimit = 1
+	}
+
+	if promo.Code == "" || promo.Value <= 0 || promo.PerUserLimit < 0 {
+		return ErrPromoNotValid
+	}
+	if promo.ValidTo != nil && !promo.ValidTo.After(promo.ValidFrom) {
+		return ErrPromoNotValid
+	}
+	if promo.MaxRedemptions != nil && *promo.MaxRedemptions < 1 {
+		return ErrPromoNotValid
+	}
+
+	return s.store.AddPromoCode(ctx, promo)
+}
+
+func (s *PromoService) RedeemPromo(ctx context.Context, userID uint64, code string) (float64, error) {
+	promo, err := s.store.RedeemPromoCode(ctx, strings.TrimSpace(code), int64(userID), time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return promo.Value, nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/store"
+	"github.com/ShvetsovYura/oygophermart/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreatePromo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockPromoStorer(ctrl)
+	ctx := context.TODO()
+	s := NewPromoService(m)
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	zero, two := 0, 2
+	// stored - запрос прошел проверку и дошел до хранилища
+	tests := []struct {
+		name   string
+		req    models.PromoReq
+		stored bool
+		err    error
+	}{
+		{"empty code", models.PromoReq{Code: " ", Value: 10}, false, ErrPromoNotValid},
+		{"zero value", models.PromoReq{Code: "SPRING", Value: 0}, false, ErrPromoNotValid},
+		{"negative per user limit", models.PromoReq{Code: "SPRING", Value: 10, PerUserLimit: -1}, false, ErrPromoNotValid},
+		{"zero global limit", models.PromoReq{Code: "SPRING", Value: 10, MaxRedemptions: &zero}, false, ErrPromoNotValid},
+		{"expired on creation", models.PromoReq{Code: "SPRING", Value: 10, ValidTo: &past}, false, ErrPromoNotValid},
+		{"valid to before valid from", models.PromoReq{Code: "SPRING", Value: 10, ValidFrom: &future, ValidTo: &future}, false, ErrPromoNotValid},
+		{"valid", models.PromoReq{Code: " SPRING ", Value: 10, ValidTo: &future, MaxRedemptions: &two}, true, nil},
+		{"duplicate", models.PromoReq{Code: "SPRING", Value: 10}, true, store.ErrPromoCodeAlreadyExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.stored {
+				m.EXPECT().AddPromoCode(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, p models.PromoCodeModel) error {
+					assert.Equal(t, "SPRING", p.Code)
+					assert.Equal(t, int64(7), p.CreatedBy)
+					assert.Equal(t, 1, p.PerUserLimit)
+					assert.Equal(t, tt.req.MaxRedemptions, p.MaxRedemptions)
+					return tt.err
+				})
+			}
+			err := s.CreatePromo(ctx, 7, tt.req)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRedeemPromo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockPromoStorer(ctrl)
+	ctx := context.TODO()
+	s := NewPromoService(m)
+
+	// лимиты и срок действия проверяет хранилище под блокировкой промокода
+	tests := []struct {
+		name  string
+		code  string
+		promo *models.PromoCodeModel
+		err   error
+	}{
+		{"redeemed", " SPRING ", &models.PromoCodeModel{Code: "SPRING", Value: 50}, nil},
+		{"not found", "SPRING", nil, store.ErrPromoCodeNotFound},
+		{"inactive or expired", "SPRING", nil, store.ErrPromoCodeNotActive},
+		{"global limit reached", "SPRING", nil, store.ErrPromoCodeExhausted},
+		{"per user limit reached", "SPRING", nil, store.ErrPromoCodeUserLimitReached},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.EXPECT().RedeemPromoCode(ctx, "SPRING", int64(1), gomock.Any()).Return(tt.promo, tt.err)
+			value, err := s.RedeemPromo(ctx, 1, tt.code)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.promo.Value, value)
+		})
+	}
+}
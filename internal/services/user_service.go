@@ -2,34 +2,56 @@ package services
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 
 	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/store"
 )
 
 var ErrUserAlreadyExists = errors.New("user already exists")
 var ErrNotValidLoginOrPassword = errors.New("not valid login/password")
 var ErrUserNotFound = errors.New("user not found")
+var ErrReferralNotFound = errors.New("referral code not found")
+var ErrReferralLimitReached = errors.New("referrer has reached the referral limit")
+var ErrSelfReferral = errors.New("self referral is not allowed")
+
+const referralCodeSize = 6
+
+// сколько раз генерировать реферальный код, если он совпал с уже выданным
+const referralCodeAttempts = 3
 
 type UserStorer interface {
-	AddUser(ctx context.Context, login string, pwdHash string) error
+	AddUser(ctx context.Context, login string, pwdHash string, referralCode string, registerIP string, referral *models.ReferralModel) error
 	GetUserByLogin(ctx context.Context, userLogin string) (*models.UserModel, error)
+	GetUserByID(ctx context.Context, userID int64) (*models.UserModel, error)
+	GetUserByReferralCode(ctx context.Context, code string) (*models.UserModel, error)
+	CountReferrals(ctx context.Context, referrerID int64) (int, error)
+	GetReferralStats(ctx context.Context, referrerID int64) (models.ReferralStatsModel, error)
 }
 
+//go:generate mockgen -destination=../../mocks/mock_hasher.go -package=mocks github.com/ShvetsovYura/oygophermart/internal/services Hasher
 type Hasher interface {
 	Hash(val string) string
+	GenerateRnd(size int) ([]byte, error)
+}
+
+type ReferralOptions struct {
+	Reward       float64
+	MaxReferrals int
 }
 
 type UserServcie struct {
-	store   UserStorer
-	hashSvc Hasher
+	store    UserStorer
+	hashSvc  Hasher
+	referral ReferralOptions
 }
 
-func NewUserService(store UserStorer, hash Hasher) *UserServcie {
-	return &UserServcie{store: store, hashSvc: hash}
+func NewUserService(store UserStorer, hash Hasher, referral ReferralOptions) *UserServcie {
+	return &UserServcie{store: store, hashSvc: hash, referral: referral}
 }
 
-func (u *UserServcie) CreateUser(ctx context.Context, login string, password string) (int64, error) {
+func (u *UserServcie) CreateUser(ctx context.Context, login string, password string, referral string, ip string) (int64, error) {
 	user, err := u.store.GetUserByLogin(ctx, login)
 	if err != nil {
 		return 0, err
@@ -38,10 +60,39 @@ func (u *UserServcie) CreateUser(ctx context.Context, login string, password str
 		return 0, ErrUserAlreadyExists
 	}
 
+	var referrer *models.UserModel
+	if referral != "" {
+		referrer, err = u.checkReferrer(ctx, referral, ip)
+		if err != nil {
+			return 0, err
+		}
+	}
+
 	hashPwd := u.hashSvc.Hash(password)
-	err = u.store.AddUser(ctx, login, hashPwd)
-	if err != nil {
-		return 0, err
+	var ref *models.ReferralModel
+	if referrer != nil {
+		ref = &models.ReferralModel{
+			ReferrerID:   referrer.ID,
+			Reward:       u.referral.Reward,
+			MaxReferrals: u.referral.MaxReferrals,
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		rnd, err := u.hashSvc.GenerateRnd(referralCodeSize)
+		if err != nil {
+			return 0, err
+		}
+		err = u.store.AddUser(ctx, login, hashPwd, hex.EncodeToString(rnd), ip, ref)
+		if errors.Is(err, store.ErrReferralCodeAlreadyExists) && attempt < referralCodeAttempts {
+			continue
+		}
+		if errors.Is(err, store.ErrLoginAlreadyExists) {
+			return 0, ErrUserAlreadyExists
+		}
+		if err != nil {
+			return 0, err
+		}
+		break
 	}
 	m, err := u.store.GetUserByLogin(ctx, login)
 	if err != nil {
@@ -50,6 +101,27 @@ func (u *UserServcie) CreateUser(ctx context.Context, login string, password str
 	return m.ID, nil
 }
 
+func (u *UserServcie) checkReferrer(ctx context.Context, referral string, ip string) (*models.UserModel, error) {
+	referrer, err := u.store.GetUserByReferralCode(ctx, referral)
+	if err != nil {
+		return nil, err
+	}
+	if referrer == nil {
+		return nil, ErrReferralNotFound
+	}
+	if ip != "" && referrer.RegisterIP != nil && *referrer.RegisterIP == ip {
+		return nil, ErrSelfReferral
+	}
+	cnt, err := u.store.CountReferrals(ctx, referrer.ID)
+	if err != nil {
+		return nil, err
+	}
+	if cnt >= u.referral.MaxReferrals {
+		return nil, ErrReferralLimitReached
+	}
+	return referrer, nil
+}
+
 func (u *UserServcie) Login(ctx context.Context, login string, password string) (int64, error) {
 	user, err := u.store.GetUserByLogin(ctx, login)
 	if err != nil {
@@ -65,3 +137,33 @@ func (u *UserServcie) Login(ctx context.Context, login string, password string)
 	}
 	return user.ID, nil
 }
+
+func (u *UserServcie) IsAdmin(ctx context.Context, userID uint64) (bool, error) {
+	user, err := u.store.GetUserByID(ctx, int64(userID))
+	if err != nil {
+		return false, err
+	}
+	if user == nil {
+		return false, ErrUserNotFound
+	}
+	return user.IsAdmin, nil
+}
+
+func (u *UserServcie) GetReferralInfo(ctx context.Context, userID uint64) (*models.ReferralResp, error) {
+	user, err := u.store.GetUserByID(ctx, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	stats, err := u.store.GetReferralStats(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ReferralResp{
+		Code:     user.ReferralCode,
+		Invited:  stats.Invited,
+		Rewarded: stats.Rewarded,
+	}, nil
+}
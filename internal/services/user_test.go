@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/store"
+	"github.com/ShvetsovYura/oygophermart/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockUserStorer(ctrl)
+	h := mocks.NewMockHasher(ctrl)
+	ctx := context.TODO()
+	s := NewUserService(m, h, ReferralOptions{Reward: 50, MaxReferrals: 1})
+
+	m.EXPECT().GetUserByLogin(ctx, "pipa").Return(&models.UserModel{ID: 1}, nil)
+	_, err := s.CreateUser(ctx, "pipa", "secret", "", "")
+	assert.ErrorIs(t, err, ErrUserAlreadyExists)
+
+	m.EXPECT().GetUserByLogin(ctx, "popa").Return(nil, nil)
+	m.EXPECT().GetUserByReferralCode(ctx, "abc").Return(nil, nil)
+	_, err = s.CreateUser(ctx, "popa", "secret", "abc", "")
+	assert.ErrorIs(t, err, ErrReferralNotFound)
+
+	m.EXPECT().GetUserByLogin(ctx, "popa").Return(nil, nil)
+	m.EXPECT().GetUserByReferralCode(ctx, "abc").Return(&models.UserModel{ID: 1}, nil)
+	m.EXPECT().CountReferrals(ctx, int64(1)).Return(1, nil)
+	_, err = s.CreateUser(ctx, "popa", "secret", "abc", "")
+	assert.ErrorIs(t, err, ErrReferralLimitReached)
+
+	gomock.InOrder(
+		m.EXPECT().GetUserByLogin(ctx, "popa").Return(nil, nil),
+		m.EXPECT().GetUserByLogin(ctx, "popa").Return(&models.UserModel{ID: 2}, nil),
+	)
+	m.EXPECT().GetUserByReferralCode(ctx, "abc").Return(&models.UserModel{ID: 1}, nil)
+	m.EXPECT().CountReferrals(ctx, int64(1)).Return(0, nil)
+	h.EXPECT().GenerateRnd(referralCodeSize).Return([]byte{0xab, 0xcd}, nil)
+	h.EXPECT().Hash("secret").Return("hash")
+	m.EXPECT().AddUser(ctx, "popa", "hash", "abcd", "", &models.ReferralModel{ReferrerID: 1, Reward: 50, MaxReferrals: 1}).Return(nil)
+	id, err := s.CreateUser(ctx, "popa", "secret", "abc", "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), id)
+
+	// лимит исчерпан параллельной регистрацией: пользователь не создан
+	m.EXPECT().GetUserByLogin(ctx, "pupa").Return(nil, nil)
+	m.EXPECT().GetUserByReferralCode(ctx, "abc").Return(&models.UserModel{ID: 1}, nil)
+	m.EXPECT().CountReferrals(ctx, int64(1)).Return(0, nil)
+	h.EXPECT().GenerateRnd(referralCodeSize).Return([]byte{0xab, 0xcd}, nil)
+	h.EXPECT().Hash("secret").Return("hash")
+	m.EXPECT().AddUser(ctx, "pupa", "hash", "abcd", "", gomock.Any()).Return(store.ErrReferralLimitReached)
+	_, err = s.CreateUser(ctx, "pupa", "secret", "abc", "")
+	assert.ErrorIs(t, err, store.ErrReferralLimitReached)
+
+	// логин занят параллельной регистрацией после проверки
+	m.EXPECT().GetUserByLogin(ctx, "pepa").Return(nil, nil)
+	h.EXPECT().GenerateRnd(referralCodeSize).Return([]byte{0xab, 0xcd}, nil)
+	h.EXPECT().Hash("secret").Return("hash")
+	m.EXPECT().AddUser(ctx, "pepa", "hash", "abcd", "", nil).Return(store.ErrLoginAlreadyExists)
+	_, err = s.CreateUser(ctx, "pepa", "secret", "", "")
+	assert.ErrorIs(t, err, ErrUserAlreadyExists)
+
+	// совпавший реферальный код генерируется заново
+	gomock.InOrder(
+		m.EXPECT().GetUserByLogin(ctx, "pepa").Return(nil, nil),
+		m.EXPECT().GetUserByLogin(ctx, "pepa").Return(&models.UserModel{ID: 3}, nil),
+	)
+	h.EXPECT().Hash("secret").Return("hash")
+	gomock.InOrder(
+		h.EXPECT().GenerateRnd(referralCodeSize).Return([]byte{0xab, 0xcd}, nil),
+		m.EXPECT().AddUser(ctx, "pepa", "hash", "abcd", "", nil).Return(store.ErrReferralCodeAlreadyExists),
+		h.EXPECT().GenerateRnd(referralCodeSize).Return([]byte{0xef, 0x01}, nil),
+		m.EXPECT().AddUser(ctx, "pepa", "hash", "ef01", "", nil).Return(nil),
+	)
+	id, err = s.CreateUser(ctx, "pepa", "secret", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), id)
+
+	// коды совпадают на каждой попытке
+	m.EXPECT().GetUserByLogin(ctx, "pepa").Return(nil, nil)
+	h.EXPECT().Hash("secret").Return("hash")
+	h.EXPECT().GenerateRnd(referralCodeSize).Return([]byte{0xab, 0xcd}, nil).Times(referralCodeAttempts)
+	m.EXPECT().AddUser(ctx, "pepa", "hash", "abcd", "", nil).Return(store.ErrReferralCodeAlreadyExists).Times(referralCodeAttempts)
+	_, err = s.CreateUser(ctx, "pepa", "secret", "", "")
+	assert.ErrorIs(t, err, store.ErrReferralCodeAlreadyExists)
+}
+
+func TestLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockUserStorer(ctrl)
+	h := mocks.NewMockHasher(ctrl)
+	ctx := context.TODO()
+	s := NewUserService(m, h, ReferralOptions{})
+
+	m.EXPECT().GetUserByLogin(ctx, "nobody").Return(nil, nil)
+	_, err := s.Login(ctx, "nobody", "secret")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	m.EXPECT().GetUserByLogin(ctx, "pipa").Return(&models.UserModel{ID: 1, PwdHash: "hash"}, nil).Times(2)
+	h.EXPECT().Hash("wrong").Return("other")
+	_, err = s.Login(ctx, "pipa", "wrong")
+	assert.ErrorIs(t, err, ErrNotValidLoginOrPassword)
+
+	h.EXPECT().Hash("secret").Return("hash")
+	id, err := s.Login(ctx, "pipa", "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+}
+
+func TestIsAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockUserStorer(ctrl)
+	ctx := context.TODO()
+	s := NewUserService(m, nil, ReferralOptions{})
+
+	m.EXPECT().GetUserByID(ctx, int64(1)).Return(&models.UserModel{ID: 1, IsAdmin: true}, nil)
+	ok, err := s.IsAdmin(ctx, 1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	m.EXPECT().GetUserByID(ctx, int64(2)).Return(nil, nil)
+	_, err = s.IsAdmin(ctx, 2)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/options"
+)
+
+var ErrWebhookDisabled = errors.New("accrual webhook is not configured for partner")
+var ErrWebhookSignature = errors.New("accrual webhook signature is not valid")
+var ErrWebhookPayload = errors.New("accrual webhook payload is not valid")
+var ErrWebhookForeignOrder = errors.New("order is not routed to the webhook partner")
+
+const webhookSignaturePrefix = "sha256="
+
+type AccrualUpdater interface {
+	UpdateOrdersStatus(ctx context.Context, processRecords ...models.AccrualResult) error
+	GetOrdersPartner(ctx context.Context, orderIDs ...string) (map[string]string, error)
+}
+
+// WebhookService принимает статусы заказов, которые партнеры присылают сами.
+// Тело запроса подписывается HMAC-SHA256 секретом партнера,
+// партнер может прислать статусы только своих заказов.
+// Заказ с неитоговым статусом опрашивается снова через pollInterval,
+// на случай если следующий статус партнер так и не пришлет.
+type WebhookService struct {
+	store        AccrualUpdater
+	partners     []options.PartnerOptions
+	secrets      map[string]string
+	pollInterval time.Duration
+}
+
+func NewWebhookService(store AccrualUpdater, partners []options.PartnerOptions, pollInterval time.Duration) *WebhookService {
+	secrets := make(map[string]string, len(partners))
+	for _, p := range partners {
+		secrets[p.Name] = p.WebhookSecret
+	}
+	return &WebhookService{store: store, partners: partners, secrets: secrets, pollInterval: pollInterval}
+}
+
+func (s *WebhookService) AccrualWebhook(ctx context.Context, partner string, signature string, body []byte) (int, error) {
+	secret, ok := s.secrets[partner]
+	if !ok || secret == "" {
+		return 0, ErrWebhookDisabled
+	}
+	if !verifySignature(secret, signature, body) {
+		return 0, ErrWebhookSignature
+	}
+	records, err := parseAccrualPayload(body)
+	if err != nil {
+		return 0, err
+	}
+	if err = s.checkOwner(ctx, partner, records); err != nil {
+		return 0, err
+	}
+	for i := range records {
+		records[i].Source = models.StatusSourceWebhook
+		records[i].NextPoll = s.pollInterval
+	}
+	if err = s.store.UpdateOrdersStatus(ctx, records...); err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
+
+// checkOwner проверяет, что все заказы принадлежат партнеру: по сохраненному
+// за заказом партнеру, а если его еще нет - по префиксу номера
+func (s *WebhookService) checkOwner(ctx context.Context, partner string, records []models.AccrualResult) error {
+	ids := make([]string, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.OrderID)
+	}
+	stored, err := s.store.GetOrdersPartner(ctx, ids...)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		owner, ok := stored[id]
+		if !ok {
+			owner = options.PartnerForOrder(s.partners, id)
+		}
+		if owner != partner {
+			return ErrWebhookForeignOrder
+		}
+	}
+	return nil
+}
+
+func verifySignature(secret string, signature string, body []byte) bool {
+	sign, err := hex.DecodeString(strings.TrimPrefix(signature, webhookSignaturePrefix))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(sign, mac.Sum(nil))
+}
+
+// parseAccrualPayload разбирает один результат или массив результатов
+func parseAccrualPayload(body []byte) ([]models.AccrualResult, error) {
+	var records []models.AccrualResult
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &records); err != nil {
+			return nil, ErrWebhookPayload
+		}
+	} else {
+		var rec models.AccrualResult
+		if err := json.Unmarshal(body, &rec); err != nil {
+			return nil, ErrWebhookPayload
+		}
+		records = append(records, rec)
+	}
+	if len(records) == 0 {
+		return nil, ErrWebhookPayload
+	}
+	for _, r := range records {
+		if _, ok := models.ParseAccrualStatus(r.Status); !ok || r.OrderID == "" {
+			return nil, ErrWebhookPayload
+		}
+		if r.Accrual != nil && *r.Accrual < 0 {
+			return nil, ErrWebhookPayload
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/ShvetsovYura/oygophermart/internal/options"
+	"github.com/stretchr/testify/assert"
+)
+
+type accrualUpdaterStub struct {
+	records  []models.AccrualResult
+	partners map[string]string
+}
+
+func (s *accrualUpdaterStub) GetOrdersPartner(ctx context.Context, orderIDs ...string) (map[string]string, error) {
+	res := make(map[string]string)
+	for _, id := range orderIDs {
+		if p, ok := s.partners[id]; ok {
+			res[id] = p
+		}
+	}
+	return res, nil
+}
+
+func (s *accrualUpdaterStub) UpdateOrdersStatus(ctx context.Context, records ...models.AccrualResult) error {
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func sign(secret string, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestAccrualWebhook(t *testing.T) {
+	store := &accrualUpdaterStub{}
+	s := NewWebhookService(store, []options.PartnerOptions{{Name: "default", WebhookSecret: "secret"}}, 10*time.Second)
+	ctx := context.Background()
+
+	single := `{"order":"12345678903","status":"PROCESSED","accrual":500}`
+	n, err := s.AccrualWebhook(ctx, "default", sign("secret", single), []byte(single))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	batch := `[{"order":"2377225624","status":"PROCESSING"},{"order":"9278923470","status":"INVALID"}]`
+	n, err = s.AccrualWebhook(ctx, "default", sign("secret", batch), []byte(batch))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Len(t, store.records, 3)
+	// опрос остается запасным путем и не запускается сразу после push
+	for _, rec := range store.records {
+		assert.Equal(t, models.StatusSourceWebhook, rec.Source)
+		assert.Equal(t, 10*time.Second, rec.NextPoll)
+	}
+
+	_, err = s.AccrualWebhook(ctx, "default", sign("other", single), []byte(single))
+	assert.ErrorIs(t, err, ErrWebhookSignature)
+
+	_, err = s.AccrualWebhook(ctx, "acme", sign("secret", single), []byte(single))
+	assert.ErrorIs(t, err, ErrWebhookDisabled)
+
+	bad := `{"order":"12345678903","status":"DONE"}`
+	_, err = s.AccrualWebhook(ctx, "default", sign("secret", bad), []byte(bad))
+	assert.ErrorIs(t, err, ErrWebhookPayload)
+	assert.Len(t, store.records, 3)
+}
+
+func TestAccrualWebhookForeignOrder(t *testing.T) {
+	store := &accrualUpdaterStub{partners: map[string]string{"12345678903": "acme"}}
+	s := NewWebhookService(store, []options.PartnerOptions{
+		{Name: "default", WebhookSecret: "secret"},
+		{Name: "acme", Prefixes: []string{"978"}, WebhookSecret: "acme-secret"},
+	}, 10*time.Second)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		partner string
+		secret  string
+		body    string
+		err     error
+	}{
+		{"stored for other partner", "default", "secret", `{"order":"12345678903","status":"PROCESSED","accrual":500}`, ErrWebhookForeignOrder},
+		{"routed by prefix to other partner", "default", "secret", `{"order":"9780306406157","status":"PROCESSED"}`, ErrWebhookForeignOrder},
+		{"one foreign order in batch", "default", "secret", `[{"order":"2377225624","status":"PROCESSING"},{"order":"12345678903","status":"INVALID"}]`, ErrWebhookForeignOrder},
+		{"default order signed by prefix partner", "acme", "acme-secret", `{"order":"2377225624","status":"PROCESSED"}`, ErrWebhookForeignOrder},
+		{"stored order of partner", "acme", "acme-secret", `{"order":"12345678903","status":"PROCESSED","accrual":500}`, nil},
+		{"prefix order of partner", "acme", "acme-secret", `{"order":"9780306406157","status":"PROCESSING"}`, nil},
+	}
+	applied := 0
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := s.AccrualWebhook(ctx, test.partner, sign(test.secret, test.body), []byte(test.body))
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+				applied++
+			}
+			assert.Len(t, store.records, applied)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const insertAccrualJobStmt = `
+	insert into accrual_job(order_id)
+	values ($1)
+	on conflict (order_id) do nothing;
+`
+
+func addAccrualJob(ctx context.Context, tx pgx.Tx, orderID string) error {
+	_, err := tx.Exec(ctx, insertAccrualJobStmt, orderID)
+	return err
+}
+
+// ClaimAccrualJobs забирает задания, время которых подошло. Строки, заблокированные
+// другими экземплярами, пропускаются, а взятым заданиям время следующей попытки
+// сдвигается на lease, чтобы их не взяли повторно, пока идет опрос.
+// Attempts - число неудачных опросов подряд, взятие задания его не меняет.
+func (s *OrderStore) ClaimAccrualJobs(ctx context.Context, limit int, lease time.Duration) ([]models.AccrualJobModel, error) {
+	var records = make([]models.AccrualJobModel, 0, limit)
+	stmt := `
+		with due as (
+			select j.order_id
+			from accrual_job j
+			where j.next_attempt_at <= now()
+			order by j.next_attempt_at
+			limit $1
+			for update skip locked
+		)
+		update accrual_job j
+		set next_attempt_at = now() + $2 * interval '1 millisecond',
+			updated_at = now()
+		from due, "order" o
+		where j.order_id = due.order_id and o.id = j.order_id and o.deleted_at is null
+		returning j.order_id, coalesce(o.partner, ''), o.status, j.attempts, j.created_at
+	`
+
+	rows, err := s.db.Query(ctx, stmt, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.AccrualJobModel
+		err = rows.Scan(&m.OrderID, &m.Partner, &m.Status, &m.Attempts, &m.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, m)
+	}
+	return records, rows.Err()
+}
+
+// FailAccrualJobs откладывает задания после неудачного опроса, сохраняет ошибку
+// и увеличивает счетчик неудач; отложенные из-за ограничений партнера задания неудачей не считаются.
+// Для заданий, по которым опрос прекращен, заказ получает статус UNKNOWN.
+func (s *OrderStore) FailAccrualJobs(ctx context.Context, failures ...models.AccrualJobFailure) error {
+	stmtReschedule := `
+		update accrual_job
+		set next_attempt_at = now() + $2 * interval '1 millisecond',
+			last_error = $3,
+			attempts = case when $4 then attempts else attempts + 1 end,
+			updated_at = now()
+		where order_id = $1
+	`
+	stmtGiveUp := `
+		with prev as (
+			select id, status from "order"
+			where id = $1 and deleted_at is null and status in ('NEW', 'PROCESSING')
+			for update
+		), upd as (
+			update "order" o
+			set status = 'UNKNOWN', updated_at = now()
+			from prev
+			where o.id = prev.id and o.deleted_at is null
+			returning o.id
+		)
+		insert into order_status_history(order_id, from_status, to_status, source)
+		select prev.id, prev.status, 'UNKNOWN', $2 from prev join upd on upd.id = prev.id
+	`
+	stmtDelJob := `delete from accrual_job where order_id = $1`
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, f := range failures {
+		if f.GiveUp {
+			batch.Queue(stmtGiveUp, f.OrderID, models.StatusSourceAgent)
+			batch.Queue(stmtDelJob, f.OrderID)
+			continue
+		}
+		batch.Queue(stmtReschedule, f.OrderID, f.RetryAfter.Milliseconds(), f.Error, f.Postponed)
+	}
+	if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,247 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ShvetsovYura/oygophermart/internal/logger"
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrDisputeOwnOrder = errors.New("order belongs to the claimant")
+var ErrDisputeAlreadyOpen = errors.New("dispute for the order is already open")
+var ErrDisputeNotFound = errors.New("dispute not found")
+var ErrDisputeAlreadyResolved = errors.New("dispute already resolved")
+var ErrDisputeStale = errors.New("order owner changed since the dispute was opened")
+var ErrDisputeWithdrawalOrder = errors.New("order is a withdrawal and cannot be disputed")
+var ErrDisputeInsufficientFunds = errors.New("owner balance does not cover the reversal")
+
+// номер заказа списания выдуман пользователем и ничего не начисляет - оспаривать нечего
+const withdrawalOrderStmt = `select exists(select 1 from loyalty where order_id = $1 and kind = 'WITHDRAWAL')`
+
+const (
+	DisputeOpen       = "OPEN"
+	DisputeReassigned = "REASSIGNED"
+	DisputeRejected   = "REJECTED"
+)
+
+type DisputeStore struct {
+	db *pgxpool.Pool
+}
+
+func NewDisputeStore(db *pgxpool.Pool) (*DisputeStore, error) {
+	return &DisputeStore{db: db}, nil
+}
+
+func (s *DisputeStore) AddDispute(ctx context.Context, orderID string, claimantID int64, evidence string) (int64, error) {
+	// блокировка не дает отменить заказ, пока открывается спор
+	ownerStmt := `select user_id from "order" where id = $1 and deleted_at is null for share`
+	insertStmt := `
+		insert into dispute(order_id, claimant_id, owner_id, evidence)
+		values ($1, $2, $3, $4)
+		returning id;
+	`
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var ownerID int64
+	err = tx.QueryRow(ctx, ownerStmt, orderID).Scan(&ownerID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrOrdersNotFoundInDB
+		}
+		return 0, err
+	}
+	if ownerID == claimantID {
+		return 0, ErrDisputeOwnOrder
+	}
+	var withdrawal bool
+	err = tx.QueryRow(ctx, withdrawalOrderStmt, orderID).Scan(&withdrawal)
+	if err != nil {
+		return 0, err
+	}
+	if withdrawal {
+		return 0, ErrDisputeWithdrawalOrder
+	}
+
+	var id int64
+	err = tx.QueryRow(ctx, insertStmt, orderID, claimantID, ownerID, evidence).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == UniqueViolation {
+				return 0, ErrDisputeAlreadyOpen
+			}
+		}
+		return 0, err
+	}
+	err = addNotification(ctx, tx, ownerID, fmt.Sprintf("Ownership of order %s is disputed by another user", orderID))
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (s *DisputeStore) GetDisputes(ctx context.Context, status string, userID *int64) ([]models.DisputeModel, error) {
+	var entities = make([]models.DisputeModel, 0)
+	stmt := `
+		select id, order_id, claimant_id, owner_id, evidence, status, comment, resolved_by, created_at, resolved_at
+		from dispute
+		where ($1 = '' or status = $1) and ($2::bigint is null or claimant_id = $2)
+		order by created_at asc, id asc
+	`
+
+	rows, err := s.db.Query(ctx, stmt, status, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.DisputeModel
+		err = rows.Scan(&m.ID, &m.OrderID, &m.ClaimantID, &m.OwnerID, &m.Evidence, &m.Status,
+			&m.Comment, &m.ResolvedBy, &m.CreatedAt, &m.ResolvedAt)
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, m)
+	}
+	return entities, rows.Err()
+}
+
+// ResolveDispute закрывает спор; при reassign заказ переходит к заявителю,
+// а уже начисленные по нему баллы переносятся между счетами в той же транзакции.
+func (s *DisputeStore) ResolveDispute(ctx context.Context, disputeID int64, adminID int64, reassign bool, comment string) error {
+	selectDisputeStmt := `
+		select order_id, claimant_id, owner_id, status
+		from dispute
+		where id = $1
+		for update;
+	`
+	lockOrderStmt := `select user_id from "order" where id = $1 and deleted_at is null for update`
+	updateOrderStmt := `update "order" set user_id = $2, updated_at = now() where id = $1 and deleted_at is null`
+	creditedStmt := `
+		select coalesce(sum("value"), 0)
+		from loyalty
+		where order_id = $1 and user_id = $2 and kind in ('ACCRUAL', 'ADJUSTMENT', 'REVERSAL')
+	`
+	insertLoyaltyStmt := `insert into loyalty(order_id, user_id, value, kind) values ($1, $2, $3, $4)`
+	updateDisputeStmt := `
+		update dispute
+		set status = $2, comment = $3, resolved_by = $4, resolved_at = now()
+		where id = $1
+	`
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var d models.DisputeModel
+	err = tx.QueryRow(ctx, selectDisputeStmt, disputeID).Scan(&d.OrderID, &d.ClaimantID, &d.OwnerID, &d.Status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrDisputeNotFound
+		}
+		return err
+	}
+	if d.Status != DisputeOpen {
+		return ErrDisputeAlreadyResolved
+	}
+
+	status := DisputeRejected
+	claimantMsg := fmt.Sprintf("Your claim for order %s was rejected", d.OrderID)
+	ownerMsg := fmt.Sprintf("Dispute for order %s was resolved in your favor", d.OrderID)
+	if reassign {
+		status = DisputeReassigned
+		claimantMsg = fmt.Sprintf("Order %s was reassigned to you", d.OrderID)
+		ownerMsg = fmt.Sprintf("Order %s was reassigned to another user after a dispute", d.OrderID)
+
+		var currentOwner int64
+		err = tx.QueryRow(ctx, lockOrderStmt, d.OrderID).Scan(&currentOwner)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrOrdersNotFoundInDB
+			}
+			return err
+		}
+		if currentOwner != d.OwnerID {
+			return ErrDisputeStale
+		}
+		var withdrawal bool
+		err = tx.QueryRow(ctx, withdrawalOrderStmt, d.OrderID).Scan(&withdrawal)
+		if err != nil {
+			return err
+		}
+		if withdrawal {
+			return ErrDisputeWithdrawalOrder
+		}
+		_, err = tx.Exec(ctx, updateOrderStmt, d.OrderID, d.ClaimantID)
+		if err != nil {
+			return err
+		}
+
+		// баланс владельца блокируется, как при списании: если баллы уже
+		// потрачены, сторно увело бы его в минус
+		var balance, credited float64
+		balance, err = lockUserBalance(ctx, tx, d.OwnerID)
+		if err != nil {
+			return err
+		}
+		err = tx.QueryRow(ctx, creditedStmt, d.OrderID, d.OwnerID).Scan(&credited)
+		if err != nil {
+			return err
+		}
+		if credited > balance {
+			return ErrDisputeInsufficientFunds
+		}
+		if credited != 0 {
+			_, err = tx.Exec(ctx, insertLoyaltyStmt, d.OrderID, d.OwnerID, -1*credited, "REVERSAL")
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, insertLoyaltyStmt, d.OrderID, d.ClaimantID, credited, "ADJUSTMENT")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if comment != "" {
+		claimantMsg += ": " + comment
+		ownerMsg += ": " + comment
+	}
+	_, err = tx.Exec(ctx, updateDisputeStmt, disputeID, status, comment, adminID)
+	if err != nil {
+		return err
+	}
+	err = addNotification(ctx, tx, d.ClaimantID, claimantMsg)
+	if err != nil {
+		return err
+	}
+	err = addNotification(ctx, tx, d.OwnerID, ownerMsg)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logger.Log.Debugf("eror on commit resolve dispute: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type NotificationStore struct {
+	db *pgxpool.Pool
+}
+
+func NewNotificationStore(db *pgxpool.Pool) (*NotificationStore, error) {
+	return &NotificationStore{db: db}, nil
+}
+
+func addNotification(ctx context.Context, tx pgx.Tx, userID int64, message string) error {
+	_, err := tx.Exec(ctx, `insert into notification(user_id, message) values ($1, $2)`, userID, message)
+	return err
+}
+
+func (s *NotificationStore) GetUserNotifications(ctx context.Context, userID int64) ([]models.NotificationModel, error) {
+	var entities = make([]models.NotificationModel, 0)
+	stmt := `
+		select id, message, created_at
+		from notification
+		where user_id = $1
+		order by created_at desc, id desc
+		limit 100
+	`
+
+	rows, err := s.db.Query(ctx, stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.NotificationModel
+		err = rows.Scan(&m.ID, &m.Message, &m.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, m)
+	}
+	return entities, rows.Err()
+}
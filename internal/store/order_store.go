@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"errors"
+	"strconv"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/ShvetsovYura/oygophermart/internal/logger"
@@ -14,6 +15,10 @@ import (
 
 var ErrOrdersNotFoundInDB = errors.New("orders not found")
 var ErrOrderAlreadyExistsInDB = errors.New("order already exists")
+var ErrInsufficientFundsInDB = errors.New("insufficient funds")
+var ErrTransferDailyLimitExceeded = errors.New("daily transfer limit exceeded")
+var ErrOrderAlreadyProcessed = errors.New("order already processed")
+var ErrOrderDisputed = errors.New("order has an open dispute")
 
 const (
 	UniqueViolation = "23505"
@@ -45,7 +50,7 @@ func (s *OrderStore) GetOrdersByID(ctx context.Context, orderID string) ([]model
 	stmt := `
 		select id, status, user_id, created_at, updated_at 
 		from "order" 
-		where id = $1
+		where id = $1 and deleted_at is null
 	`
 
 	rows, err := s.db.Query(ctx, stmt, orderID)
@@ -76,6 +81,7 @@ func (s *OrderStore) GetUserOrders(ctx context.Context, userID uint64) ([]models
 		LEFT JOIN LOYALTY L ON O.ID = L.ORDER_ID
 	WHERE
 		O.USER_ID = $1
+		AND O.DELETED_AT IS NULL
 	GROUP BY
 		O.ID,
 		O.STATUS,
@@ -98,13 +104,81 @@ func (s *OrderStore) GetUserOrders(ctx context.Context, userID uint64) ([]models
 	return entities, nil
 }
 
+func (s *OrderStore) GetUserOrdersPage(ctx context.Context, userID uint64, filter models.OrderFilter) ([]models.OrderGroupedModel, error) {
+	var entities = make([]models.OrderGroupedModel, 0, filter.Limit)
+
+	q := sq.Select(
+		"O.ID",
+		"O.STATUS",
+		"O.CREATED_AT",
+		"O.UPDATED_AT",
+		`SUM(L."value") FILTER (WHERE L.KIND = 'ACCRUAL') as val`,
+	).
+		From(`"order" O`).
+		LeftJoin("LOYALTY L ON O.ID = L.ORDER_ID").
+		Where(sq.Eq{"O.USER_ID": userID}).
+		Where("O.DELETED_AT IS NULL").
+		Where(`NOT EXISTS (SELECT 1 FROM LOYALTY W WHERE W.ORDER_ID = O.ID AND W.KIND = 'WITHDRAWAL')`)
+
+	if len(filter.Statuses) > 0 {
+		q = q.Where(sq.Eq{"O.STATUS": filter.Statuses})
+	}
+	if filter.From != nil {
+		q = q.Where(sq.GtOrEq{"O.CREATED_AT": *filter.From})
+	}
+	if filter.To != nil {
+		q = q.Where(sq.Lt{"O.CREATED_AT": *filter.To})
+	}
+	direction := "ASC"
+	if filter.Desc {
+		direction = "DESC"
+	}
+	if filter.After != nil {
+		if filter.Desc {
+			q = q.Where("(O.CREATED_AT, O.ID) < (?, ?)", filter.After.At, filter.After.ID)
+		} else {
+			q = q.Where("(O.CREATED_AT, O.ID) > (?, ?)", filter.After.At, filter.After.ID)
+		}
+	}
+
+	stmt, args, err := q.GroupBy("O.ID", "O.STATUS", "O.CREATED_AT", "O.UPDATED_AT").
+		OrderBy("O.CREATED_AT "+direction, "O.ID "+direction).
+		Limit(uint64(filter.Limit)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.OrderGroupedModel
+		err = rows.Scan(&m.ID, &m.Status, &m.CreatedAt, &m.UpdatedAt, &m.Accrual)
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, m)
+	}
+	return entities, rows.Err()
+}
+
 func (s *OrderStore) AddNewOrder(ctx context.Context, userID int64, orderID string) error {
 	stmt, args, _ := sq.Insert(`"order"`).
 		Columns("id", "status", "user_id").
 		Values(orderID, "NEW", userID).
 		PlaceholderFormat(sq.Dollar).ToSql()
 
-	_, err := s.db.Exec(ctx, stmt, args...)
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, stmt, args...)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if ok := errors.As(err, &pgErr); ok {
@@ -114,26 +188,222 @@ func (s *OrderStore) AddNewOrder(ctx context.Context, userID int64, orderID stri
 		}
 		return err
 	}
-	return nil
+	err = addAccrualJob(ctx, tx, orderID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (s *OrderStore) GetUserOrderByID(ctx context.Context, orderID string, userID int64) (*models.LoyaltyOrderModel, error) {
-	var m models.LoyaltyOrderModel
+// AddNewOrders добавляет заказы одной транзакцией и возвращает результат по каждому номеру.
+func (s *OrderStore) AddNewOrders(ctx context.Context, userID int64, orderIDs []string) (map[string]string, error) {
+	insertStmt := `
+		insert into "order"(id, status, user_id)
+		values ($1, 'NEW', $2)
+		on conflict (id) where deleted_at is null do nothing;
+	`
+	ownerStmt := `select user_id from "order" where id = $1 and deleted_at is null`
+
+	result := make(map[string]string, len(orderIDs))
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, orderID := range orderIDs {
+		if _, ok := result[orderID]; ok {
+			continue
+		}
+		tag, err := tx.Exec(ctx, insertStmt, orderID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() == 1 {
+			err = addAccrualJob(ctx, tx, orderID)
+			if err != nil {
+				return nil, err
+			}
+			result[orderID] = models.BulkOrderAccepted
+			continue
+		}
+
+		var ownerID int64
+		err = tx.QueryRow(ctx, ownerStmt, orderID).Scan(&ownerID)
+		if err != nil {
+			return nil, err
+		}
+		if ownerID == userID {
+			result[orderID] = models.BulkOrderDuplicateOwn
+		} else {
+			result[orderID] = models.BulkOrderDuplicateForeign
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logger.Log.Debugf("eror on commit bulk orders: %v", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// ImportOrders сохраняет загружаемые заказы одной транзакцией, строки с
+// неизвестным пользователем или уже существующим номером возвращаются как ошибки.
+// При dryRun транзакция откатывается.
+func (s *OrderStore) ImportOrders(ctx context.Context, rows []models.ImportOrderModel, dryRun bool) ([]models.ImportErrorModel, error) {
+	userStmt := `select "id" from "user" where login = $1`
+	insertOrderStmt := `
+		insert into "order"(id, status, user_id, created_at, updated_at)
+		values ($1, $2, $3, coalesce($4, now()), coalesce($4, now()))
+		on conflict (id) where deleted_at is null do nothing;
+	`
+	insertLoyaltyStmt := `
+		insert into loyalty(order_id, user_id, value, kind)
+		values ($1, $2, $3, 'ACCRUAL');
+	`
+
+	var rowErrors []models.ImportErrorModel
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	users := make(map[string]int64)
+	for _, row := range rows {
+		userID, ok := users[row.Login]
+		if !ok {
+			err = tx.QueryRow(ctx, userStmt, row.Login).Scan(&userID)
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					rowErrors = append(rowErrors, models.ImportErrorModel{Line: row.Line, OrderID: row.OrderID, Error: "user not found"})
+					continue
+				}
+				return nil, err
+			}
+			users[row.Login] = userID
+		}
+
+		tag, err := tx.Exec(ctx, insertOrderStmt, row.OrderID, row.Status, userID, row.UploadedAt)
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() == 0 {
+			rowErrors = append(rowErrors, models.ImportErrorModel{Line: row.Line, OrderID: row.OrderID, Error: ErrOrderAlreadyExistsInDB.Error()})
+			continue
+		}
+		if row.Status == "NEW" || row.Status == "PROCESSING" {
+			err = addAccrualJob(ctx, tx, row.OrderID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if row.Accrual != nil {
+			_, err = tx.Exec(ctx, insertLoyaltyStmt, row.OrderID, userID, *row.Accrual)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if dryRun {
+		return rowErrors, nil
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		logger.Log.Debugf("eror on commit import orders: %v", err)
+		return nil, err
+	}
+	return rowErrors, nil
+}
+
+// CancelOrder помечает заказ удаленным, строка остается в таблице для аудита.
+// Заказ с открытым спором отменить нельзя, пока спор не разрешен.
+func (s *OrderStore) CancelOrder(ctx context.Context, orderID string, userID int64) error {
+	lockStmt := `select id from "order" where id = $1 and user_id = $2 and deleted_at is null for update`
+	disputeStmt := `select exists(select 1 from dispute where order_id = $1 and status = 'OPEN')`
+	cancelStmt := `
+		with prev as (
+			select id, status from "order"
+			where id = $1 and user_id = $2 and deleted_at is null and status in ('NEW', 'PROCESSING')
+			for update
+		), upd as (
+			update "order" o
+			set deleted_at = now(), updated_at = now(), status = 'CANCELLED'
+			from prev
+			where o.id = prev.id and o.deleted_at is null
+			returning o.id
+		)
+		insert into order_status_history(order_id, from_status, to_status, source)
+		select prev.id, prev.status, 'CANCELLED', $3 from prev join upd on upd.id = prev.id
+	`
+	deleteJobStmt := `delete from accrual_job where order_id = $1`
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// блокировка заказа упорядочивает отмену с открытием спора в AddDispute
+	var id string
+	err = tx.QueryRow(ctx, lockStmt, orderID, userID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrOrdersNotFoundInDB
+		}
+		return err
+	}
+	var disputed bool
+	err = tx.QueryRow(ctx, disputeStmt, orderID).Scan(&disputed)
+	if err != nil {
+		return err
+	}
+	if disputed {
+		return ErrOrderDisputed
+	}
+
+	tag, err := tx.Exec(ctx, cancelStmt, orderID, userID, models.StatusSourceUser)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOrderAlreadyProcessed
+	}
+	_, err = tx.Exec(ctx, deleteJobStmt, orderID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *OrderStore) GetUserOrderByID(ctx context.Context, orderID string, userID int64) (*models.OrderDetailModel, error) {
+	var m models.OrderDetailModel
 	stmt := `
 	SELECT
-		ID,
-		STATUS,
-		CREATED_AT,
-		UPDATED_AT
+		O.ID,
+		O.STATUS,
+		SUM(L."value") FILTER (WHERE L.KIND = 'ACCRUAL') AS ACCRUAL,
+		O.CREATED_AT,
+		O.UPDATED_AT
 	FROM
 		"order" O
+		LEFT JOIN LOYALTY L ON O.ID = L.ORDER_ID
 	WHERE
 		O.USER_ID = $1
-		AND O.ID = $2;
+		AND O.ID = $2
+		AND O.DELETED_AT IS NULL
+		AND NOT EXISTS (SELECT 1 FROM LOYALTY W WHERE W.ORDER_ID = O.ID AND W.KIND = 'WITHDRAWAL')
+	GROUP BY
+		O.ID,
+		O.STATUS,
+		O.CREATED_AT,
+		O.UPDATED_AT;
 	`
 
 	row := s.db.QueryRow(ctx, stmt, userID, orderID)
-	err := row.Scan(&m.ID, &m.Status, &m.Value, &m.UserID, &m.CreatedAt, &m.UpdatedAt)
+	err := row.Scan(&m.ID, &m.Status, &m.Accrual, &m.CreatedAt, &m.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrOrdersNotFoundInDB
@@ -144,50 +414,69 @@ func (s *OrderStore) GetUserOrderByID(ctx context.Context, orderID string, userI
 	return &m, nil
 }
 
-func (s *OrderStore) GetUserBalance(ctx context.Context, userID uint64) models.BalanceModel {
-	var m models.BalanceModel
+func (s *OrderStore) GetOrderPolls(ctx context.Context, orderID string) ([]models.OrderPollModel, error) {
+	var entities = make([]models.OrderPollModel, 0)
 	stmt := `
+		select p.status, p.accrual, p.polled_at
+		from order_poll p
+		inner join "order" o on o.id = p.order_id and o.deleted_at is null
+		where p.order_id = $1 and p.polled_at >= o.created_at
+		order by p.polled_at asc, p.id asc
+	`
+
+	rows, err := s.db.Query(ctx, stmt, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.OrderPollModel
+		err = rows.Scan(&m.Status, &m.Accrual, &m.PolledAt)
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, m)
+	}
+	return entities, rows.Err()
+}
+
+const userBalanceStmt = `
 	SELECT
-		-- U.ID AS UID,
-		COALESCE(WD, 0) AS ACCRUED,
-		COALESCE(WD1, 0) AS WITHDRAWN,
-		CAST(COALESCE(WD, 0.0) + COALESCE(WD1, 0.0) as numeric(10, 4)) AS BALANCE
+		COALESCE(SUM(L."value") FILTER (WHERE L."value" > 0), 0) AS ACCRUED,
+		COALESCE(SUM(L."value") FILTER (WHERE L.KIND = 'WITHDRAWAL'), 0) AS WITHDRAWN,
+		CAST(COALESCE(SUM(L."value"), 0.0) as numeric(10, 4)) AS BALANCE
 	FROM
-		"user" U
-		LEFT JOIN (
-			SELECT
-				O.USER_ID AS UID,
-				SUM(L."value") AS WD
-			FROM
-				"order" O
-				INNER JOIN LOYALTY L ON O."id" = L.ORDER_ID
-			WHERE
-				O.STATUS = 'PROCESSED'
-				AND L."value" > 0
-			GROUP BY
-				O.USER_ID
-		) W ON U."id" = W.UID
-		LEFT JOIN (
-			SELECT
-				O.USER_ID AS UID,
-				SUM(L."value") AS WD1
-			FROM
-				"order" O
-				INNER JOIN LOYALTY L ON O."id" = L.ORDER_ID
-			WHERE
-				O.STATUS = 'PROCESSED'
-				AND L."value" < 0
-			GROUP BY
-				O.USER_ID
-		) W1 ON U."id" = W1.UID
+		LOYALTY L
+		LEFT JOIN "order" O ON O."id" = L.ORDER_ID AND O.DELETED_AT IS NULL
 	WHERE
-		U.ID = $1;
+		L.USER_ID = $1
+		AND (L.ORDER_ID IS NULL OR O.STATUS = 'PROCESSED');
 	`
-	s.db.QueryRow(ctx, stmt, userID).Scan(&m.Accrued, &m.Withdrawn, &m.Balance)
+
+func (s *OrderStore) GetUserBalance(ctx context.Context, userID uint64) models.BalanceModel {
+	var m models.BalanceModel
+	s.db.QueryRow(ctx, userBalanceStmt, userID).Scan(&m.Accrued, &m.Withdrawn, &m.Balance)
 	logger.Log.Debugf("user %d balance %v", userID, m)
 	return m
 }
 
+// lockUserBalance блокирует строку пользователя до конца транзакции,
+// чтобы параллельные списания не ушли в минус, и возвращает текущий баланс.
+func lockUserBalance(ctx context.Context, tx pgx.Tx, userID int64) (float64, error) {
+	var id int64
+	err := tx.QueryRow(ctx, `select "id" from "user" where "id" = $1 for update`, userID).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	var m models.BalanceModel
+	err = tx.QueryRow(ctx, userBalanceStmt, userID).Scan(&m.Accrued, &m.Withdrawn, &m.Balance)
+	if err != nil {
+		return 0, err
+	}
+	return m.Balance, nil
+}
+
 func (s *OrderStore) Withdraw(ctx context.Context, orderID string, userID int64, value float64) error {
 	insertOrderStmt := `
 		insert into "order"(id, user_id, status)
@@ -195,8 +484,8 @@ func (s *OrderStore) Withdraw(ctx context.Context, orderID string, userID int64,
 	`
 
 	insertLoyaltyStmt := `
-		insert into loyalty(order_id, value)
-		values ($1, $2)
+		insert into loyalty(order_id, user_id, value, kind)
+		values ($1, $2, $3, 'WITHDRAWAL')
 	`
 	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -205,12 +494,20 @@ func (s *OrderStore) Withdraw(ctx context.Context, orderID string, userID int64,
 		return err
 	}
 	defer tx.Rollback(ctx)
+	balance, err := lockUserBalance(ctx, tx, userID)
+	if err != nil {
+		logger.Log.Debugf("error on lock balance withdraw: %v", err)
+		return err
+	}
+	if balance < value {
+		return ErrInsufficientFundsInDB
+	}
 	_, err = tx.Exec(ctx, insertOrderStmt, orderID, userID, "PROCESSED")
 	if err != nil {
 		logger.Log.Debugf("error on exec insert order withdraw: %e", err)
 		return ErrOrderAlreadyExistsInDB
 	}
-	_, err = tx.Exec(ctx, insertLoyaltyStmt, orderID, -1*value)
+	_, err = tx.Exec(ctx, insertLoyaltyStmt, orderID, userID, -1*value)
 	if err != nil {
 		logger.Log.Debugf("error on exec insert loyalty withdraw: %e", err)
 		tx.Rollback(ctx)
@@ -225,57 +522,326 @@ func (s *OrderStore) Withdraw(ctx context.Context, orderID string, userID int64,
 	return nil
 }
 
+func (s *OrderStore) Transfer(ctx context.Context, fromUserID int64, toUserID int64, value float64, limits models.TransferLimits) error {
+	dailyStmt := `
+		select count(*), coalesce(sum("value"), 0)
+		from transfer
+		where from_user_id = $1 and created_at >= date_trunc('day', now());
+	`
+	insertTransferStmt := `
+		insert into transfer(from_user_id, to_user_id, "value")
+		values ($1, $2, $3)
+		returning id;
+	`
+	insertLoyaltyStmt := `
+		insert into loyalty(user_id, value, kind, transfer_id)
+		values ($1, $2, $3, $4);
+	`
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	balance, err := lockUserBalance(ctx, tx, fromUserID)
+	if err != nil {
+		logger.Log.Debugf("error on lock balance transfer: %v", err)
+		return err
+	}
+	if balance < value {
+		return ErrInsufficientFundsInDB
+	}
+
+	var cnt int
+	var sum float64
+	err = tx.QueryRow(ctx, dailyStmt, fromUserID).Scan(&cnt, &sum)
+	if err != nil {
+		return err
+	}
+	if limits.Count > 0 && cnt+1 > limits.Count {
+		return ErrTransferDailyLimitExceeded
+	}
+	if limits.Sum > 0 && sum+value > limits.Sum {
+		return ErrTransferDailyLimitExceeded
+	}
+
+	var transferID int64
+	err = tx.QueryRow(ctx, insertTransferStmt, fromUserID, toUserID, value).Scan(&transferID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, insertLoyaltyStmt, fromUserID, -1*value, "TRANSFER_OUT", transferID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, insertLoyaltyStmt, toUserID, value, "TRANSFER_IN", transferID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logger.Log.Debugf("eror on commit transfer: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s *OrderStore) GetUserTransfers(ctx context.Context, userID int64) ([]models.TransferModel, error) {
+	var entities = make([]models.TransferModel, 0)
+	stmt := `
+	SELECT
+		T.ID,
+		T.FROM_USER_ID,
+		UF.LOGIN,
+		T.TO_USER_ID,
+		UT.LOGIN,
+		T."value",
+		T.CREATED_AT
+	FROM
+		TRANSFER T
+		INNER JOIN "user" UF ON UF."id" = T.FROM_USER_ID
+		INNER JOIN "user" UT ON UT."id" = T.TO_USER_ID
+	WHERE
+		T.FROM_USER_ID = $1
+		OR T.TO_USER_ID = $1
+	ORDER BY
+		T.CREATED_AT ASC;
+	`
+
+	rows, err := s.db.Query(ctx, stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.TransferModel
+		err = rows.Scan(&m.ID, &m.FromUserID, &m.FromLogin, &m.ToUserID, &m.ToLogin, &m.Value, &m.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, m)
+	}
+	return entities, rows.Err()
+}
+
+func (s *OrderStore) GetUserHistory(ctx context.Context, userID int64, filter models.HistoryFilter) ([]models.HistoryRecordModel, error) {
+	var entities = make([]models.HistoryRecordModel, 0, filter.Limit)
+
+	// баланс считается по всем движениям до фильтрации,
+	// поэтому он остается корректным при любых фильтрах
+	movements := sq.Select(
+		"L.ID",
+		"L.KIND",
+		"L.ORDER_ID",
+		`L."value"`,
+		"L.CREATED_AT",
+		`SUM(L."value") OVER (ORDER BY L.CREATED_AT, L.ID) AS BALANCE`,
+	).
+		From("LOYALTY L").
+		LeftJoin(`"order" O ON O."id" = L.ORDER_ID AND O.DELETED_AT IS NULL`).
+		Where(sq.Eq{"L.USER_ID": userID}).
+		Where("(L.ORDER_ID IS NULL OR O.STATUS = 'PROCESSED')")
+
+	q := sq.Select("H.ID", "H.KIND", "H.ORDER_ID", `H."value"`, "H.CREATED_AT", "H.BALANCE").
+		FromSelect(movements, "H")
+	if len(filter.Kinds) > 0 {
+		q = q.Where(sq.Eq{"H.KIND": filter.Kinds})
+	}
+	if filter.From != nil {
+		q = q.Where(sq.GtOrEq{"H.CREATED_AT": *filter.From})
+	}
+	if filter.To != nil {
+		q = q.Where(sq.Lt{"H.CREATED_AT": *filter.To})
+	}
+	if filter.After != nil {
+		afterID, err := strconv.ParseInt(filter.After.ID, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Where("(H.CREATED_AT, H.ID) > (?, ?)", filter.After.At, afterID)
+	}
+	stmt, args, err := q.OrderBy("H.CREATED_AT ASC", "H.ID ASC").
+		Limit(uint64(filter.Limit)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.HistoryRecordModel
+		err = rows.Scan(&m.ID, &m.Kind, &m.OrderID, &m.Value, &m.CreatedAt, &m.Balance)
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, m)
+	}
+	return entities, rows.Err()
+}
+
 func (s *OrderStore) UpdateOrdersStatus(ctx context.Context, processRecords ...models.AccrualResult) error {
-	statusMap := map[string]string{
-		"REGISTERED": "PROCESSING",
-		"PROCESSING": "PROCESSING",
-		"INVALID":    "INVALID",
-		"PROCESSED":  "PROCESSED",
-	}
-	stmtUpdOrder := `update "order" set status = $1 where id = $2`
-	stmtInsLyalty := `insert into loyalty (order_id, value) values($1, $2) `
+	stmtUpdOrder := `
+		update "order"
+		set status = $1, updated_at = case when status <> $1 then now() else updated_at end
+		where id = $2 and deleted_at is null
+	`
+	stmtInsPoll := `
+		insert into order_poll (order_id, status, accrual)
+		select id, $2, $3 from "order" where id = $1 and deleted_at is null
+	`
+	stmtInsLyalty := `
+		insert into loyalty (order_id, user_id, value, kind)
+		select id, user_id, $2, 'ACCRUAL' from "order" where id = $1 and deleted_at is null
+		on conflict (order_id) where kind = 'ACCRUAL' do nothing
+	`
+	// вознаграждение за приглашение начисляется обоим один раз,
+	// когда первый заказ приглашенного переходит в PROCESSED
+	stmtReferralReward := `
+		with ref as (
+			update referral r set rewarded_at = now()
+			from "order" o
+			where o.id = $1 and o.deleted_at is null and r.referee_id = o.user_id and r.rewarded_at is null
+			returning r.referrer_id, r.referee_id, r.reward
+		)
+		insert into loyalty (user_id, value, kind)
+		select referrer_id, reward, 'REFERRAL' from ref
+		union all
+		select referee_id, reward, 'REFERRAL' from ref
+	`
+	stmtDelJob := `delete from accrual_job where order_id = $1`
+	stmtLockOrder := `select status from "order" where id = $1 and deleted_at is null for update`
+	stmtInsHistory := `
+		insert into order_status_history(order_id, from_status, to_status, source)
+		values ($1, $2, $3, $4)
+	`
+	stmtRescheduleJob := `
+		update accrual_job
+		set next_attempt_at = now() + $2 * interval '1 millisecond', attempts = 0, last_error = null, updated_at = now()
+		where order_id = $1
+	`
+	apply := func(tx pgx.Tx, inRec models.AccrualResult) error {
+		// статус может прийти и из опроса, и от партнера; недопустимый переход
+		// (например, из итогового статуса) пропускается, чтобы повторная доставка
+		// не начислила баллы дважды
+		var current models.OrderStatus
+		err := tx.QueryRow(ctx, stmtLockOrder, inRec.OrderID).Scan(&current)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		status, ok := models.ParseAccrualStatus(inRec.Status)
+		if ok && !current.CanTransitionTo(status) {
+			logger.Log.Debugf("skip order %s transition %s -> %s", inRec.OrderID, current, status)
+			return nil
+		}
+		_, err = tx.Exec(ctx, stmtInsPoll, inRec.OrderID, inRec.Status, inRec.Accrual)
+		if err != nil {
+			logger.Log.Debugf("err on save order poll: %v", err)
+			return err
+		}
+		if ok {
+			_, err = tx.Exec(ctx, stmtUpdOrder, status, inRec.OrderID)
+			if err != nil {
+				logger.Log.Debugf("err on update order status: %v", err)
+				return err
+			}
+			if status != current {
+				source := inRec.Source
+				if source == "" {
+					source = models.StatusSourcePoll
+				}
+				_, err = tx.Exec(ctx, stmtInsHistory, inRec.OrderID, current, status, source)
+				if err != nil {
+					logger.Log.Debugf("err on save status history: %v", err)
+					return err
+				}
+			}
+			// баллы начисляются только вместе с переходом в PROCESSED
+			if status == models.OrderProcessed && inRec.Accrual != nil {
+				_, err = tx.Exec(ctx, stmtInsLyalty, inRec.OrderID, *inRec.Accrual)
+				if err != nil {
+					logger.Log.Debugf("err on save accrual: %v", err)
+					return err
+				}
+			}
+			if status == models.OrderProcessed {
+				_, err = tx.Exec(ctx, stmtReferralReward, inRec.OrderID)
+				if err != nil {
+					logger.Log.Debugf("err on referral reward: %v", err)
+					return err
+				}
+			}
+			if status.Final() {
+				_, err = tx.Exec(ctx, stmtDelJob, inRec.OrderID)
+			} else {
+				_, err = tx.Exec(ctx, stmtRescheduleJob, inRec.OrderID, inRec.NextPoll.Milliseconds())
+			}
+			if err != nil {
+				logger.Log.Debugf("err on update accrual job: %v", err)
+				return err
+			}
+		}
+		return nil
+	}
+
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
+	// ошибка любой записи откатывает всю пачку, вызывающий повторит ее целиком
 	for _, inRec := range processRecords {
-		if status, ok := statusMap[inRec.Status]; ok {
-			tx.Exec(ctx, stmtUpdOrder, status, inRec.OrderID)
-			if inRec.Accrual != nil {
-				tx.Exec(ctx, stmtInsLyalty, inRec.OrderID, *inRec.Accrual)
-			}
+		if err = apply(tx, inRec); err != nil {
+			return err
 		}
 	}
 	err = tx.Commit(ctx)
 	if err != nil {
 		logger.Log.Debugf("err commint: %e", err)
-		tx.Rollback(ctx)
 		return err
 	}
 	return nil
 }
 
-func (s *OrderStore) GetOrdersToAccrualProcess(ctx context.Context) ([]models.OrderModel, error) {
-	var records = make([]models.OrderModel, 0, 10)
-	stmt := `
-		select id, user_id, status, created_at, updated_at 
-		from "order" o 
-		where o.status not in ('INVALID', 'PROCESSED')
-	`
-
-	rows, err := s.db.Query(ctx, stmt)
+// GetOrdersPartner возвращает партнеров, за которыми уже закреплены заказы
+func (s *OrderStore) GetOrdersPartner(ctx context.Context, orderIDs ...string) (map[string]string, error) {
+	stmt := `select id, partner from "order" where id = any($1) and partner is not null and deleted_at is null`
+	rows, err := s.db.Query(ctx, stmt, orderIDs)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
+	res := make(map[string]string, len(orderIDs))
 	for rows.Next() {
-		var m models.OrderModel
-		err = rows.Scan(&m.ID, &m.UserID, &m.Status, &m.CreateedAt, &m.UpdatedAt)
-		if err != nil {
+		var id, partner string
+		if err = rows.Scan(&id, &partner); err != nil {
 			return nil, err
 		}
-		records = append(records, m)
+		res[id] = partner
 	}
-	return records, nil
+	return res, rows.Err()
+}
+
+func (s *OrderStore) AssignOrdersPartner(ctx context.Context, partner string, orderIDs ...string) error {
+	stmt := `
+		update "order"
+		set partner = $1
+		where id = any($2) and partner is null and deleted_at is null
+	`
+	_, err := s.db.Exec(ctx, stmt, partner, orderIDs)
+	if err != nil {
+		return err
+	}
+	return nil
 }
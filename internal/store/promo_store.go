@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ShvetsovYura/oygophermart/internal/logger"
+	"github.com/ShvetsovYura/oygophermart/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrPromoCodeAlreadyExists = errors.New("promo code already exists")
+var ErrPromoCodeNotFound = errors.New("promo code not found")
+var ErrPromoCodeNotActive = errors.New("promo code is not active")
+var ErrPromoCodeExhausted = errors.New("promo code redemption limit reached")
+var ErrPromoCodeUserLimitReached = errors.New("promo code user redemption limit reached")
+
+type PromoStore struct {
+	db *pgxpool.Pool
+}
+
+func NewPromoStore(db *pgxpool.Pool) (*PromoStore, error) {
+	return &PromoStore{db: db}, nil
+}
+
+func (s *PromoStore) AddPromoCode(ctx context.Context, promo models.PromoCodeModel) error {
+	stmt := `
+		insert into promo_code(code, "value", valid_from, valid_to, max_redemptions, per_user_limit, created_by)
+		values ($1, $2, $3, $4, $5, $6, $7);
+	`
+	_, err := s.db.Exec(ctx, stmt,
+		promo.Code, promo.Value, promo.ValidFrom, promo.ValidTo,
+		promo.MaxRedemptions, promo.PerUserLimit, promo.CreatedBy,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok {
+			if pgErr.Code == UniqueViolation {
+				return ErrPromoCodeAlreadyExists
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// RedeemPromoCode проверяет лимиты и начисляет баллы в одной транзакции.
+// Строка промокода блокируется, чтобы параллельные погашения не превысили лимит.
+func (s *PromoStore) RedeemPromoCode(ctx context.Context, code string, userID int64, now time.Time) (*models.PromoCodeModel, error) {
+	selectPromoStmt := `
+		select code, "value", valid_from, valid_to, max_redemptions, per_user_limit, created_by, created_at
+		from promo_code
+		where code = $1
+		for update;
+	`
+	countStmt := `
+		select count(*), count(*) filter (where user_id = $2)
+		from promo_redemption
+		where code = $1;
+	`
+	insertLoyaltyStmt := `
+		insert into loyalty(user_id, value, kind)
+		values ($1, $2, 'PROMO')
+		returning id;
+	`
+	insertRedemptionStmt := `
+		insert into promo_redemption(code, user_id, loyalty_id)
+		values ($1, $2, $3);
+	`
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var p models.PromoCodeModel
+	err = tx.QueryRow(ctx, selectPromoStmt, code).Scan(
+		&p.Code, &p.Value, &p.ValidFrom, &p.ValidTo, &p.MaxRedemptions, &p.PerUserLimit, &p.CreatedBy, &p.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPromoCodeNotFound
+		}
+		return nil, err
+	}
+	if now.Before(p.ValidFrom) || (p.ValidTo != nil && !now.Before(*p.ValidTo)) {
+		return nil, ErrPromoCodeNotActive
+	}
+
+	var total, byUser int
+	err = tx.QueryRow(ctx, countStmt, code, userID).Scan(&total, &byUser)
+	if err != nil {
+		return nil, err
+	}
+	if p.MaxRedemptions != nil && total >= *p.MaxRedemptions {
+		return nil, ErrPromoCodeExhausted
+	}
+	if byUser >= p.PerUserLimit {
+		return nil, ErrPromoCodeUserLimitReached
+	}
+
+	var loyaltyID int64
+	err = tx.QueryRow(ctx, insertLoyaltyStmt, userID, p.Value).Scan(&loyaltyID)
+	if err != nil {
+		logger.Log.Debugf("error on insert promo loyalty: %v", err)
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, insertRedemptionStmt, code, userID, loyaltyID)
+	if err != nil {
+		logger.Log.Debugf("error on insert promo redemption: %v", err)
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
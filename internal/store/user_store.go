@@ -6,9 +6,14 @@ import (
 
 	"github.com/ShvetsovYura/oygophermart/internal/models"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var ErrReferralLimitReached = errors.New("referral limit reached")
+var ErrLoginAlreadyExists = errors.New("login already exists")
+var ErrReferralCodeAlreadyExists = errors.New("referral code already exists")
+
 type UserStore struct {
 	db *pgxpool.Pool
 }
@@ -17,13 +22,59 @@ func NewUserStore(db *pgxpool.Pool) (*UserStore, error) {
 	return &UserStore{db: db}, nil
 }
 
-func (s *UserStore) AddUser(ctx context.Context, login string, pwdHash string) error {
-	stmt := `insert into "user"(login, pwd_hash) values($1, $2);`
-	_, err := s.db.Exec(ctx, stmt, login, pwdHash)
+// AddUser создает пользователя и, если он приглашен, приглашение в одной транзакции:
+// при превышении лимита приглашений пользователь не создается.
+// Лимит проверяется под блокировкой строки пригласившего,
+// чтобы параллельные регистрации не превысили MaxReferrals.
+// Занятые логин или реферальный код возвращаются как ErrLoginAlreadyExists
+// и ErrReferralCodeAlreadyExists.
+func (s *UserStore) AddUser(ctx context.Context, login string, pwdHash string, referralCode string, registerIP string, referral *models.ReferralModel) error {
+	insertUserStmt := `insert into "user"(login, pwd_hash, referral_code, register_ip) values($1, $2, $3, $4) returning "id";`
+	lockStmt := `select "id" from "user" where "id" = $1 for update`
+	countStmt := `select count(*) from referral where referrer_id = $1`
+	insertReferralStmt := `insert into referral(referee_id, referrer_id, reward) values ($1, $2, $3)`
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int64
+	err = tx.QueryRow(ctx, insertUserStmt, login, pwdHash, referralCode, registerIP).Scan(&userID)
 	if err != nil {
+		// логин мог занять параллельный запрос после проверки в сервисе
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok && pgErr.Code == UniqueViolation {
+			switch pgErr.ConstraintName {
+			case "unique_login":
+				return ErrLoginAlreadyExists
+			case "unique_referral_code":
+				return ErrReferralCodeAlreadyExists
+			}
+		}
 		return err
 	}
-	return nil
+	if referral != nil {
+		var id int64
+		err = tx.QueryRow(ctx, lockStmt, referral.ReferrerID).Scan(&id)
+		if err != nil {
+			return err
+		}
+		var cnt int
+		err = tx.QueryRow(ctx, countStmt, referral.ReferrerID).Scan(&cnt)
+		if err != nil {
+			return err
+		}
+		if cnt >= referral.MaxReferrals {
+			return ErrReferralLimitReached
+		}
+		_, err = tx.Exec(ctx, insertReferralStmt, userID, referral.ReferrerID, referral.Reward)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
 }
 
 func (s *UserStore) GetUserByLogin(ctx context.Context, userLogin string) (*models.UserModel, error) {
@@ -31,7 +82,10 @@ func (s *UserStore) GetUserByLogin(ctx context.Context, userLogin string) (*mode
 		SELECT
 			"id",
 			login,
-			pwd_hash
+			pwd_hash,
+			is_admin,
+			referral_code,
+			register_ip
 		FROM
 			"user"
 		WHERE
@@ -41,7 +95,36 @@ func (s *UserStore) GetUserByLogin(ctx context.Context, userLogin string) (*mode
 	row := s.db.QueryRow(ctx, stmt, userLogin)
 
 	var u models.UserModel
-	err := row.Scan(&u.ID, &u.Login, &u.PwdHash)
+	err := row.Scan(&u.ID, &u.Login, &u.PwdHash, &u.IsAdmin, &u.ReferralCode, &u.RegisterIP)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &u, nil
+
+}
+
+func (s *UserStore) GetUserByID(ctx context.Context, userID int64) (*models.UserModel, error) {
+	stmt := `
+		SELECT
+			"id",
+			login,
+			pwd_hash,
+			is_admin,
+			referral_code,
+			register_ip
+		FROM
+			"user"
+		WHERE
+			"id" = $1
+	`
+
+	row := s.db.QueryRow(ctx, stmt, userID)
+
+	var u models.UserModel
+	err := row.Scan(&u.ID, &u.Login, &u.PwdHash, &u.IsAdmin, &u.ReferralCode, &u.RegisterIP)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -49,5 +132,56 @@ func (s *UserStore) GetUserByLogin(ctx context.Context, userLogin string) (*mode
 		return nil, err
 	}
 	return &u, nil
+}
+
+func (s *UserStore) GetUserByReferralCode(ctx context.Context, code string) (*models.UserModel, error) {
+	stmt := `
+		SELECT
+			"id",
+			login,
+			pwd_hash,
+			is_admin,
+			referral_code,
+			register_ip
+		FROM
+			"user"
+		WHERE
+			referral_code = $1
+	`
 
+	row := s.db.QueryRow(ctx, stmt, code)
+
+	var u models.UserModel
+	err := row.Scan(&u.ID, &u.Login, &u.PwdHash, &u.IsAdmin, &u.ReferralCode, &u.RegisterIP)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s *UserStore) CountReferrals(ctx context.Context, referrerID int64) (int, error) {
+	var cnt int
+	stmt := `select count(*) from referral where referrer_id = $1`
+	err := s.db.QueryRow(ctx, stmt, referrerID).Scan(&cnt)
+	if err != nil {
+		return 0, err
+	}
+	return cnt, nil
+}
+
+func (s *UserStore) GetReferralStats(ctx context.Context, referrerID int64) (models.ReferralStatsModel, error) {
+	var m models.ReferralStatsModel
+	stmt := `
+		select count(*), count(*) filter (where rewarded_at is not null)
+		from referral
+		where referrer_id = $1
+	`
+	err := s.db.QueryRow(ctx, stmt, referrerID).Scan(&m.Invited, &m.Rewarded)
+	if err != nil {
+		return m, err
+	}
+	return m, nil
 }
@@ -0,0 +1,277 @@
+package utils
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+var ErrUnknownOrderScheme = errors.New("unknown order number scheme")
+
+const (
+	SchemeLuhn     = "luhn"
+	SchemeVerhoeff = "verhoeff"
+	SchemeDamm     = "damm"
+	SchemeMod11    = "mod11"
+	SchemeAlnum    = "alnum"
+)
+
+type OrderNumberValidator interface {
+	Name() string
+	Validate(number string) bool
+}
+
+func digits(number string) ([]uint8, bool) {
+	if len(number) < 1 {
+		return nil, false
+	}
+	var res = make([]uint8, 0, len(number))
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return nil, false
+		}
+		res = append(res, uint8(r-'0'))
+	}
+	return res, true
+}
+
+type LuhnValidator struct{}
+
+func (LuhnValidator) Name() string { return SchemeLuhn }
+
+func (LuhnValidator) Validate(number string) bool {
+	code, ok := digits(number)
+	return ok && CheckLuhn(code)
+}
+
+var verhoeffD = [10][10]uint8{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+	{1, 2, 3, 4, 0, 6, 7, 8, 9, 5},
+	{2, 3, 4, 0, 1, 7, 8, 9, 5, 6},
+	{3, 4, 0, 1, 2, 8, 9, 5, 6, 7},
+	{4, 0, 1, 2, 3, 9, 5, 6, 7, 8},
+	{5, 9, 8, 7, 6, 0, 4, 3, 2, 1},
+	{6, 5, 9, 8, 7, 1, 0, 4, 3, 2},
+	{7, 6, 5, 9, 8, 2, 1, 0, 4, 3},
+	{8, 7, 6, 5, 9, 3, 2, 1, 0, 4},
+	{9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+}
+
+var verhoeffP = [8][10]uint8{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+	{1, 5, 7, 6, 2, 8, 3, 0, 9, 4},
+	{5, 8, 0, 3, 7, 9, 6, 1, 4, 2},
+	{8, 9, 1, 6, 0, 4, 3, 5, 2, 7},
+	{9, 4, 5, 3, 1, 2, 6, 8, 7, 0},
+	{4, 2, 8, 6, 5, 7, 3, 9, 0, 1},
+	{2, 7, 9, 3, 8, 0, 6, 4, 1, 5},
+	{7, 0, 4, 6, 9, 1, 3, 2, 5, 8},
+}
+
+type VerhoeffValidator struct{}
+
+func (VerhoeffValidator) Name() string { return SchemeVerhoeff }
+
+func (VerhoeffValidator) Validate(number string) bool {
+	code, ok := digits(number)
+	if !ok {
+		return false
+	}
+	var c uint8
+	for i := range code {
+		c = verhoeffD[c][verhoeffP[i%8][code[len(code)-1-i]]]
+	}
+	return c == 0
+}
+
+var dammTable = [10][10]uint8{
+	{0, 3, 1, 7, 5, 9, 8, 6, 4, 2},
+	{7, 0, 9, 2, 1, 5, 4, 8, 6, 3},
+	{4, 2, 0, 6, 8, 7, 1, 3, 5, 9},
+	{1, 7, 5, 0, 9, 8, 3, 4, 2, 6},
+	{6, 1, 2, 3, 0, 4, 5, 9, 7, 8},
+	{3, 6, 7, 4, 2, 0, 9, 5, 8, 1},
+	{5, 8, 6, 9, 7, 2, 0, 1, 3, 4},
+	{8, 9, 4, 5, 3, 6, 2, 0, 1, 7},
+	{9, 4, 3, 8, 6, 1, 7, 2, 0, 5},
+	{2, 5, 8, 1, 4, 3, 6, 7, 9, 0},
+}
+
+type DammValidator struct{}
+
+func (DammValidator) Name() string { return SchemeDamm }
+
+func (DammValidator) Validate(number string) bool {
+	code, ok := digits(number)
+	if !ok {
+		return false
+	}
+	var interim uint8
+	for _, d := range code {
+		interim = dammTable[interim][d]
+	}
+	return interim == 0
+}
+
+// Mod11Validator проверяет номер как ISBN-10: веса от длины номера до 1,
+// последний символ может быть X (10), сумма должна делиться на 11.
+type Mod11Validator struct{}
+
+func (Mod11Validator) Name() string { return SchemeMod11 }
+
+func (Mod11Validator) Validate(number string) bool {
+	if len(number) < 2 {
+		return false
+	}
+	var sum int
+	n := len(number)
+	for i, r := range number {
+		var d int
+		switch {
+		case r >= '0' && r <= '9':
+			d = int(r - '0')
+		case (r == 'X' || r == 'x') && i == n-1:
+			d = 10
+		default:
+			return false
+		}
+		sum += d * (n - i)
+	}
+	return sum%11 == 0
+}
+
+const alnumAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// AlnumValidator проверяет буквенно-цифровые номера партнеров алгоритмом Luhn mod 36.
+type AlnumValidator struct{}
+
+func (AlnumValidator) Name() string { return SchemeAlnum }
+
+func (AlnumValidator) Validate(number string) bool {
+	if len(number) < 2 {
+		return false
+	}
+	n := len(alnumAlphabet)
+	factor := 1
+	sum := 0
+	for i := len(number) - 1; i >= 0; i-- {
+		cp := strings.IndexByte(alnumAlphabet, number[i])
+		if cp < 0 {
+			return false
+		}
+		addend := factor * cp
+		if factor == 1 {
+			factor = 2
+		} else {
+			factor = 1
+		}
+		sum += addend/n + addend%n
+	}
+	return sum%n == 0
+}
+
+type prefixRule struct {
+	prefix    string
+	validator OrderNumberValidator
+}
+
+// OrderNumberRegistry выбирает схему проверки номера заказа по самому длинному
+// совпавшему префиксу, иначе используется схема по умолчанию.
+type OrderNumberRegistry struct {
+	schemes  map[string]OrderNumberValidator
+	prefixes []prefixRule
+	def      OrderNumberValidator
+}
+
+func NewOrderNumberRegistry() *OrderNumberRegistry {
+	r := &OrderNumberRegistry{
+		schemes: make(map[string]OrderNumberValidator),
+		def:     LuhnValidator{},
+	}
+	for _, v := range []OrderNumberValidator{
+		LuhnValidator{}, VerhoeffValidator{}, DammValidator{}, Mod11Validator{}, AlnumValidator{},
+	} {
+		r.Register(v)
+	}
+	return r
+}
+
+func (r *OrderNumberRegistry) Register(v OrderNumberValidator) {
+	r.schemes[v.Name()] = v
+}
+
+func (r *OrderNumberRegistry) scheme(name string) (OrderNumberValidator, error) {
+	v, ok := r.schemes[name]
+	if !ok {
+		return nil, ErrUnknownOrderScheme
+	}
+	return v, nil
+}
+
+func (r *OrderNumberRegistry) SetDefault(scheme string) error {
+	v, err := r.scheme(scheme)
+	if err != nil {
+		return err
+	}
+	r.def = v
+	return nil
+}
+
+func (r *OrderNumberRegistry) AddPrefix(prefix string, scheme string) error {
+	v, err := r.scheme(scheme)
+	if err != nil {
+		return err
+	}
+	r.prefixes = append(r.prefixes, prefixRule{prefix: prefix, validator: v})
+	sort.SliceStable(r.prefixes, func(i, j int) bool {
+		return len(r.prefixes[i].prefix) > len(r.prefixes[j].prefix)
+	})
+	return nil
+}
+
+// AddPartner проверяет номера с префиксами партнера его схемой, а если схема
+// не задана - схемой по умолчанию, так что номер проверяется схемой того партнера,
+// к которому он будет направлен на опрос. Вызывается после SetDefault.
+func (r *OrderNumberRegistry) AddPartner(prefixes []string, scheme string) error {
+	name := r.def.Name()
+	if scheme != "" {
+		name = strings.ToLower(scheme)
+	}
+	for _, prefix := range prefixes {
+		if err := r.AddPrefix(prefix, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseSchemes разбирает правила вида "prefix=scheme,prefix=scheme".
+func (r *OrderNumberRegistry) ParseSchemes(rules string) error {
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		prefix, scheme, found := strings.Cut(rule, "=")
+		if !found || prefix == "" {
+			return ErrUnknownOrderScheme
+		}
+		if err := r.AddPrefix(prefix, strings.ToLower(scheme)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *OrderNumberRegistry) ValidatorFor(number string) OrderNumberValidator {
+	for _, rule := range r.prefixes {
+		if strings.HasPrefix(number, rule.prefix) {
+			return rule.validator
+		}
+	}
+	return r.def
+}
+
+func (r *OrderNumberRegistry) Validate(number string) bool {
+	return r.ValidatorFor(number).Validate(number)
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderNumberValidators(t *testing.T) {
+	tests := []struct {
+		validator OrderNumberValidator
+		input     string
+		expected  bool
+	}{
+		{validator: LuhnValidator{}, input: "34544556646468", expected: true},
+		{validator: LuhnValidator{}, input: "12345678903", expected: true},
+		{validator: LuhnValidator{}, input: "345445566464", expected: false},
+		{validator: LuhnValidator{}, input: "", expected: false},
+		{validator: LuhnValidator{}, input: "1234a", expected: false},
+
+		{validator: VerhoeffValidator{}, input: "2363", expected: true},
+		{validator: VerhoeffValidator{}, input: "2364", expected: false},
+		{validator: VerhoeffValidator{}, input: "14", expected: false},
+		{validator: VerhoeffValidator{}, input: "2361", expected: false},
+		{validator: VerhoeffValidator{}, input: "", expected: false},
+		{validator: VerhoeffValidator{}, input: "23a3", expected: false},
+
+		{validator: DammValidator{}, input: "5724", expected: true},
+		{validator: DammValidator{}, input: "5727", expected: false},
+		{validator: DammValidator{}, input: "0", expected: true},
+		{validator: DammValidator{}, input: "", expected: false},
+		{validator: DammValidator{}, input: "57-4", expected: false},
+
+		{validator: Mod11Validator{}, input: "0306406152", expected: true},
+		{validator: Mod11Validator{}, input: "080442957X", expected: true},
+		{validator: Mod11Validator{}, input: "080442957x", expected: true},
+		{validator: Mod11Validator{}, input: "0306406153", expected: false},
+		{validator: Mod11Validator{}, input: "0X06406152", expected: false},
+		{validator: Mod11Validator{}, input: "1", expected: false},
+
+		{validator: AlnumValidator{}, input: "AB12345H", expected: true},
+		{validator: AlnumValidator{}, input: "GM7Q2KG", expected: true},
+		{validator: AlnumValidator{}, input: "ZZ00010", expected: true},
+		{validator: AlnumValidator{}, input: "AB12345G", expected: false},
+		{validator: AlnumValidator{}, input: "ab12345H", expected: false},
+		{validator: AlnumValidator{}, input: "AB-12345H", expected: false},
+		{validator: AlnumValidator{}, input: "A", expected: false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.validator.Validate(test.input), "%s %q", test.validator.Name(), test.input)
+	}
+}
+
+func TestOrderNumberRegistry(t *testing.T) {
+	r := NewOrderNumberRegistry()
+	assert.NoError(t, r.ParseSchemes("AB=alnum, 978=mod11,97=damm"))
+	assert.NoError(t, r.SetDefault(SchemeLuhn))
+	assert.NoError(t, r.AddPartner([]string{"23"}, "Verhoeff"))
+	assert.NoError(t, r.AddPartner([]string{"9781"}, ""))
+
+	tests := []struct {
+		input    string
+		scheme   string
+		expected bool
+	}{
+		{input: "12345678903", scheme: SchemeLuhn, expected: true},
+		{input: "AB12345H", scheme: SchemeAlnum, expected: true},
+		{input: "AB12345G", scheme: SchemeAlnum, expected: false},
+		{input: "9780306406", scheme: SchemeMod11, expected: false},
+		{input: "9724", scheme: SchemeDamm, expected: false},
+		{input: "2363", scheme: SchemeVerhoeff, expected: true},
+		// префикс партнера без схемы длиннее правила 978=mod11 - схема по умолчанию
+		{input: "97816", scheme: SchemeLuhn, expected: true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, r.Validate(test.input), test.input)
+		assert.Equal(t, test.scheme, r.ValidatorFor(test.input).Name(), test.input)
+	}
+
+	assert.ErrorIs(t, r.ParseSchemes("AB=unknown"), ErrUnknownOrderScheme)
+	assert.ErrorIs(t, r.ParseSchemes("AB"), ErrUnknownOrderScheme)
+	assert.ErrorIs(t, r.SetDefault("unknown"), ErrUnknownOrderScheme)
+}
@@ -1,22 +1,28 @@
 package webserver
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/ShvetsovYura/oygophermart/internal/logger"
+	"github.com/ShvetsovYura/oygophermart/internal/models"
 	"github.com/ShvetsovYura/oygophermart/internal/options"
 	"github.com/ShvetsovYura/oygophermart/internal/router"
 	"github.com/ShvetsovYura/oygophermart/internal/services"
 	"github.com/ShvetsovYura/oygophermart/internal/store"
+	"github.com/ShvetsovYura/oygophermart/internal/utils"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type WebServer struct {
 	router  *router.HTTPRouter
 	options *options.AppOptions
+	server  *http.Server
 }
 
-func NewWebServer(dbConn *pgxpool.Pool, opt *options.AppOptions) (*WebServer, error) {
+func NewWebServer(dbConn *pgxpool.Pool, opt *options.AppOptions, accrualMonitor router.AccrualMonitor) (*WebServer, error) {
 	orderStore, err := store.NewOrderStore(dbConn)
 	if err != nil {
 		return nil, err
@@ -25,22 +31,86 @@ func NewWebServer(dbConn *pgxpool.Pool, opt *options.AppOptions) (*WebServer, er
 	if err != nil {
 		return nil, err
 	}
+	promoStore, err := store.NewPromoStore(dbConn)
+	if err != nil {
+		return nil, err
+	}
+	disputeStore, err := store.NewDisputeStore(dbConn)
+	if err != nil {
+		return nil, err
+	}
+	notificationStore, err := store.NewNotificationStore(dbConn)
+	if err != nil {
+		return nil, err
+	}
 	hasher := services.NewHashService()
+	validator := utils.NewOrderNumberRegistry()
+	err = validator.ParseSchemes(opt.OrderSchemes)
+	if err != nil {
+		return nil, err
+	}
+	partners, err := opt.Partners()
+	if err != nil {
+		return nil, err
+	}
+	// номера без префикса партнера уходят партнеру по умолчанию - проверяем их его схемой
+	for _, p := range partners {
+		if p.Name == options.DefaultPartner && p.Scheme != "" {
+			if err = validator.SetDefault(strings.ToLower(p.Scheme)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, p := range partners {
+		if err = validator.AddPartner(p.Prefixes, p.Scheme); err != nil {
+			return nil, err
+		}
+	}
 
 	router := router.NewHTTPRouter(
-		services.NewOrderService(orderStore, userStore),
-		services.NewUserService(userStore, hasher),
+		services.NewOrderService(orderStore, userStore, models.TransferLimits{
+			Sum:   opt.TransferDailySum,
+			Count: opt.TransferDailyMax,
+		}, validator),
+		services.NewUserService(userStore, hasher, services.ReferralOptions{
+			Reward:       opt.ReferralReward,
+			MaxReferrals: opt.MaxReferrals,
+		}),
 		hasher,
+		services.NewPromoService(promoStore),
+		services.NewDisputeService(disputeStore, notificationStore),
+		validator,
+		accrualMonitor,
+		services.NewWebhookService(orderStore, partners, opt.AccrualPollInterval),
+		dbConn,
 	)
 
+	router.InitRouter()
+	// сервер создается здесь, а не в Start, чтобы Shutdown из другой горутины
+	// не читал поле, которое Start еще не успел записать
 	return &WebServer{
 		router:  router,
 		options: opt,
+		server:  &http.Server{Addr: opt.RunAddr, Handler: router.GetRouter()},
 	}, nil
 }
 
-func (ws *WebServer) Start() {
-	ws.router.InitRouter()
+// Handler возвращает собранные маршруты; используется и в тестах без запуска сервера
+func (ws *WebServer) Handler() http.Handler {
+	return ws.server.Handler
+}
+
+// Start блокирует до остановки сервера; после Shutdown возвращает nil
+func (ws *WebServer) Start() error {
 	logger.Log.Debugf("start on: %s", ws.options.RunAddr)
-	http.ListenAndServe(ws.options.RunAddr, ws.router.GetRouter())
+	err := ws.server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown перестает принимать соединения и ждет завершения текущих запросов до отмены ctx
+func (ws *WebServer) Shutdown(ctx context.Context) error {
+	return ws.server.Shutdown(ctx)
 }
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table loyalty add column user_id bigint null;
+alter table loyalty add column kind text not null default 'ACCRUAL';
+alter table loyalty alter column order_id drop not null;
+
+update loyalty l set user_id = o.user_id from "order" o where o.id = l.order_id;
+update loyalty set kind = 'WITHDRAWAL' where "value" < 0;
+
+alter table loyalty alter column user_id set not null;
+alter table loyalty add constraint loyalty_user_fk foreign key (user_id) references "user"("id");
+create index if not exists loyalty_user_id_idx on loyalty(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists loyalty_user_id_idx;
+delete from loyalty where order_id is null;
+alter table loyalty alter column order_id set not null;
+alter table loyalty drop column kind;
+alter table loyalty drop column user_id;
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table "user" add column is_admin boolean not null default false;
+
+create table if not exists promo_code
+(
+	code text not null,
+	"value" double precision not null,
+	valid_from timestamp with time zone not null default now(),
+	valid_to timestamp with time zone null,
+	max_redemptions integer null,
+	per_user_limit integer not null default 1,
+	created_by bigint not null,
+	created_at timestamp with time zone not null default now(),
+	constraint promo_code_pkey primary key (code),
+	constraint promo_code_value_check check ("value" > 0),
+	constraint promo_code_user_fk foreign key (created_by) references "user"("id")
+);
+
+create table if not exists promo_redemption
+(
+	id bigserial not null,
+	code text not null,
+	user_id bigint not null,
+	loyalty_id bigint not null,
+	created_at timestamp with time zone not null default now(),
+	constraint promo_redemption_pkey primary key (id),
+	constraint promo_redemption_code_fk foreign key (code) references promo_code(code),
+	constraint promo_redemption_user_fk foreign key (user_id) references "user"("id"),
+	constraint promo_redemption_loyalty_fk foreign key (loyalty_id) references loyalty(id)
+);
+create index if not exists promo_redemption_code_user_idx on promo_redemption(code, user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists promo_redemption;
+drop table if exists promo_code;
+alter table "user" drop column is_admin;
+-- +goose StatementEnd
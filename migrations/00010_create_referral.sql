@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table "user" add column referral_code text null;
+alter table "user" add column register_ip text null;
+update "user" set referral_code = substr(md5(random()::text || "id"::text), 1, 12) where referral_code is null;
+alter table "user" alter column referral_code set not null;
+alter table "user" add constraint unique_referral_code unique (referral_code);
+
+create table if not exists referral
+(
+	referee_id bigint not null,
+	referrer_id bigint not null,
+	reward double precision not null,
+	created_at timestamp with time zone not null default now(),
+	rewarded_at timestamp with time zone null,
+	constraint referral_pkey primary key (referee_id),
+	constraint referral_referee_fk foreign key (referee_id) references "user"("id"),
+	constraint referral_referrer_fk foreign key (referrer_id) references "user"("id"),
+	constraint referral_self_check check (referee_id <> referrer_id)
+);
+create index if not exists referral_referrer_id_idx on referral(referrer_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists referral;
+alter table "user" drop constraint unique_referral_code;
+alter table "user" drop column register_ip;
+alter table "user" drop column referral_code;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists transfer
+(
+	id bigserial not null,
+	from_user_id bigint not null,
+	to_user_id bigint not null,
+	"value" double precision not null,
+	created_at timestamp with time zone not null default now(),
+	constraint transfer_pkey primary key (id),
+	constraint transfer_from_user_fk foreign key (from_user_id) references "user"("id"),
+	constraint transfer_to_user_fk foreign key (to_user_id) references "user"("id"),
+	constraint transfer_value_check check ("value" > 0),
+	constraint transfer_self_check check (from_user_id <> to_user_id)
+);
+create index if not exists transfer_from_user_created_idx on transfer(from_user_id, created_at);
+create index if not exists transfer_to_user_idx on transfer(to_user_id);
+
+alter table loyalty add column transfer_id bigint null;
+alter table loyalty add constraint loyalty_transfer_fk foreign key (transfer_id) references transfer("id");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+delete from loyalty where transfer_id is not null;
+alter table loyalty drop column transfer_id;
+drop table if exists transfer;
+-- +goose StatementEnd
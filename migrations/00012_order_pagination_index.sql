@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+create index if not exists order_user_created_idx on "order"(user_id, created_at, "id");
+create index if not exists order_user_status_idx on "order"(user_id, status);
+create index if not exists loyalty_order_id_idx on loyalty(order_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists loyalty_order_id_idx;
+drop index if exists order_user_status_idx;
+drop index if exists order_user_created_idx;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists order_poll
+(
+	id bigserial not null,
+	order_id text not null,
+	status text not null,
+	accrual double precision null,
+	polled_at timestamp with time zone not null default now(),
+	constraint order_poll_pkey primary key (id),
+	constraint order_poll_order_fk foreign key (order_id) references "order"("id")
+);
+create index if not exists order_poll_order_id_idx on order_poll(order_id, polled_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists order_poll;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- отмененный заказ остается в таблице, поэтому номер уникален только среди неудаленных;
+-- внешние ключи на "order"(id) не могут ссылаться на частичный индекс и удаляются
+alter table loyalty drop constraint if exists order_fk;
+alter table order_poll drop constraint if exists order_poll_order_fk;
+alter table "order" drop constraint loyalty_order_pkey;
+
+alter table "order" add column deleted_at timestamp with time zone null;
+create unique index if not exists order_id_active_idx on "order"("id") where deleted_at is null;
+create index if not exists order_id_idx on "order"("id");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+delete from order_poll p where not exists (select 1 from "order" o where o.id = p.order_id and o.deleted_at is null);
+delete from "order" where deleted_at is not null;
+drop index if exists order_id_idx;
+drop index if exists order_id_active_idx;
+alter table "order" drop column deleted_at;
+
+alter table "order" add constraint loyalty_order_pkey primary key ("id");
+alter table order_poll add constraint order_poll_order_fk foreign key (order_id) references "order"("id");
+alter table loyalty add constraint order_fk foreign key (order_id) references "order"("id");
+-- +goose StatementEnd
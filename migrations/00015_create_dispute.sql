@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists dispute
+(
+	id bigserial not null,
+	order_id text not null,
+	claimant_id bigint not null,
+	owner_id bigint not null,
+	evidence text not null,
+	status text not null default 'OPEN',
+	comment text null,
+	resolved_by bigint null,
+	created_at timestamp with time zone not null default now(),
+	resolved_at timestamp with time zone null,
+	constraint dispute_pkey primary key (id),
+	constraint dispute_claimant_fk foreign key (claimant_id) references "user"("id"),
+	constraint dispute_owner_fk foreign key (owner_id) references "user"("id"),
+	constraint dispute_resolved_by_fk foreign key (resolved_by) references "user"("id")
+);
+create unique index if not exists dispute_open_idx on dispute(order_id, claimant_id) where status = 'OPEN';
+create index if not exists dispute_status_idx on dispute(status, created_at);
+
+create table if not exists notification
+(
+	id bigserial not null,
+	user_id bigint not null,
+	message text not null,
+	created_at timestamp with time zone not null default now(),
+	constraint notification_pkey primary key (id),
+	constraint notification_user_fk foreign key (user_id) references "user"("id")
+);
+create index if not exists notification_user_idx on notification(user_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists notification;
+drop table if exists dispute;
+-- +goose StatementEnd
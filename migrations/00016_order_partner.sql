@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table "order" add column partner text null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table "order" drop column partner;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+create table if not exists accrual_job
+(
+	order_id text not null,
+	next_attempt_at timestamp with time zone not null default now(),
+	attempts integer not null default 0,
+	last_error text null,
+	created_at timestamp with time zone not null default now(),
+	updated_at timestamp with time zone not null default now(),
+	constraint accrual_job_pkey primary key (order_id)
+);
+create index if not exists accrual_job_next_attempt_idx on accrual_job(next_attempt_at);
+
+insert into accrual_job(order_id)
+select id from "order"
+where status not in ('INVALID', 'PROCESSED') and deleted_at is null
+on conflict (order_id) do nothing;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists accrual_job;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- начисление по заказу применяется один раз; дубли от повторной обработки удаляются
+delete from loyalty l
+using loyalty d
+where l.kind = 'ACCRUAL' and d.kind = 'ACCRUAL' and l.order_id = d.order_id and l.id > d.id;
+
+create unique index if not exists loyalty_order_accrual_idx on loyalty(order_id) where kind = 'ACCRUAL';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists loyalty_order_accrual_idx;
+-- +goose StatementEnd
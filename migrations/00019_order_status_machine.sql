@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+update "order" set status = 'PROCESSING' where status = 'REGISTERED';
+alter table "order" add constraint order_status_check
+	check (status in ('NEW', 'PROCESSING', 'INVALID', 'PROCESSED', 'UNKNOWN', 'CANCELLED'));
+
+-- переходы дублируют models.orderTransitions, чтобы их нельзя было обойти прямым update
+create or replace function order_status_transition_check() returns trigger as $$
+begin
+	if new.status = old.status then
+		return new;
+	end if;
+	if (old.status = 'NEW' and new.status in ('PROCESSING', 'INVALID', 'PROCESSED', 'UNKNOWN', 'CANCELLED'))
+		or (old.status = 'PROCESSING' and new.status in ('INVALID', 'PROCESSED', 'UNKNOWN', 'CANCELLED'))
+		or (old.status = 'UNKNOWN' and new.status in ('PROCESSING', 'INVALID', 'PROCESSED')) then
+		return new;
+	end if;
+	raise exception 'order % status transition % -> % is not allowed', old.id, old.status, new.status
+		using errcode = 'check_violation';
+end;
+$$ language plpgsql;
+
+create trigger order_status_transition
+	before update of status on "order"
+	for each row execute function order_status_transition_check();
+
+create table if not exists order_status_history
+(
+	id bigserial not null,
+	order_id text not null,
+	from_status text not null,
+	to_status text not null,
+	source text not null,
+	created_at timestamp with time zone not null default now(),
+	constraint order_status_history_pkey primary key (id)
+);
+create index if not exists order_status_history_order_idx on order_status_history(order_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists order_status_history;
+drop trigger if exists order_status_transition on "order";
+drop function if exists order_status_transition_check();
+alter table "order" drop constraint if exists order_status_check;
+-- +goose StatementEnd
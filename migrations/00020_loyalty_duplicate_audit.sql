@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 00018 уже применена на действующих базах, поэтому аудит дублей начислений
+-- добавляется отдельно: оставшиеся дубли не удаляются, а переносятся в
+-- loyalty_duplicate_audit, чтобы списанные баллы можно было сверить
+create table if not exists loyalty_duplicate_audit
+(
+	id bigint not null,
+	order_id text,
+	user_id bigint not null,
+	"value" double precision,
+	kind text not null,
+	created_at timestamp with time zone not null,
+	updated_at timestamp with time zone not null,
+	moved_at timestamp with time zone not null default now(),
+	constraint loyalty_duplicate_audit_pkey primary key(id)
+);
+
+insert into loyalty_duplicate_audit(id, order_id, user_id, "value", kind, created_at, updated_at)
+select l.id, l.order_id, l.user_id, l."value", l.kind, l.created_at, l.updated_at
+from loyalty l
+where l.kind = 'ACCRUAL' and exists (
+	select 1 from loyalty d
+	where d.kind = 'ACCRUAL' and d.order_id = l.order_id and d.id < l.id
+)
+on conflict (id) do nothing;
+
+delete from loyalty l
+using loyalty_duplicate_audit a
+where l.id = a.id;
+
+create unique index if not exists loyalty_order_accrual_idx on loyalty(order_id) where kind = 'ACCRUAL';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists loyalty_duplicate_audit;
+-- +goose StatementEnd
@@ -3,7 +3,6 @@ package migrations
 import (
 	"database/sql"
 	"embed"
-	"log"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/pressly/goose/v3"
@@ -12,23 +11,18 @@ import (
 //go:embed *.sql
 var embedMigrations embed.FS
 
-func RunUpMigration(dsn string) {
-	var db *sql.DB
-	var err error
-
-	db, err = sql.Open("pgx", dsn)
+func RunUpMigration(dsn string) error {
+	db, err := sql.Open("pgx", dsn)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	defer db.Close()
 
 	goose.SetBaseFS(embedMigrations)
 
 	if err := goose.SetDialect("postgres"); err != nil {
-		panic(err)
-	}
-
-	if err := goose.Up(db, "."); err != nil {
-		panic(err)
+		return err
 	}
 
+	return goose.Up(db, ".")
 }
@@ -0,0 +1,118 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ShvetsovYura/oygophermart/internal/services (interfaces: DisputeStorer,NotificationStorer)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/ShvetsovYura/oygophermart/internal/models"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockDisputeStorer is a mock of DisputeStorer interface.
+type MockDisputeStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockDisputeStorerMockRecorder
+}
+
+// MockDisputeStorerMockRecorder is the mock recorder for MockDisputeStorer.
+type MockDisputeStorerMockRecorder struct {
+	mock *MockDisputeStorer
+}
+
+// NewMockDisputeStorer creates a new mock instance.
+func NewMockDisputeStorer(ctrl *gomock.Controller) *MockDisputeStorer {
+	mock := &MockDisputeStorer{ctrl: ctrl}
+	mock.recorder = &MockDisputeStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDisputeStorer) EXPECT() *MockDisputeStorerMockRecorder {
+	return m.recorder
+}
+
+// AddDispute mocks base method.
+func (m *MockDisputeStorer) AddDispute(arg0 context.Context, arg1 string, arg2 int64, arg3 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDispute", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddDispute indicates an expected call of AddDispute.
+func (mr *MockDisputeStorerMockRecorder) AddDispute(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDispute", reflect.TypeOf((*MockDisputeStorer)(nil).AddDispute), arg0, arg1, arg2, arg3)
+}
+
+// GetDisputes mocks base method.
+func (m *MockDisputeStorer) GetDisputes(arg0 context.Context, arg1 string, arg2 *int64) ([]models.DisputeModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDisputes", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.DisputeModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDisputes indicates an expected call of GetDisputes.
+func (mr *MockDisputeStorerMockRecorder) GetDisputes(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDisputes", reflect.TypeOf((*MockDisputeStorer)(nil).GetDisputes), arg0, arg1, arg2)
+}
+
+// ResolveDispute mocks base method.
+func (m *MockDisputeStorer) ResolveDispute(arg0 context.Context, arg1, arg2 int64, arg3 bool, arg4 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveDispute", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResolveDispute indicates an expected call of ResolveDispute.
+func (mr *MockDisputeStorerMockRecorder) ResolveDispute(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveDispute", reflect.TypeOf((*MockDisputeStorer)(nil).ResolveDispute), arg0, arg1, arg2, arg3, arg4)
+}
+
+// MockNotificationStorer is a mock of NotificationStorer interface.
+type MockNotificationStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotificationStorerMockRecorder
+}
+
+// MockNotificationStorerMockRecorder is the mock recorder for MockNotificationStorer.
+type MockNotificationStorerMockRecorder struct {
+	mock *MockNotificationStorer
+}
+
+// NewMockNotificationStorer creates a new mock instance.
+func NewMockNotificationStorer(ctrl *gomock.Controller) *MockNotificationStorer {
+	mock := &MockNotificationStorer{ctrl: ctrl}
+	mock.recorder = &MockNotificationStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotificationStorer) EXPECT() *MockNotificationStorerMockRecorder {
+	return m.recorder
+}
+
+// GetUserNotifications mocks base method.
+func (m *MockNotificationStorer) GetUserNotifications(arg0 context.Context, arg1 int64) ([]models.NotificationModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserNotifications", arg0, arg1)
+	ret0, _ := ret[0].([]models.NotificationModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserNotifications indicates an expected call of GetUserNotifications.
+func (mr *MockNotificationStorerMockRecorder) GetUserNotifications(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserNotifications", reflect.TypeOf((*MockNotificationStorer)(nil).GetUserNotifications), arg0, arg1)
+}
@@ -0,0 +1,66 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ShvetsovYura/oygophermart/internal/services (interfaces: PromoStorer)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	models "github.com/ShvetsovYura/oygophermart/internal/models"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockPromoStorer is a mock of PromoStorer interface.
+type MockPromoStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockPromoStorerMockRecorder
+}
+
+// MockPromoStorerMockRecorder is the mock recorder for MockPromoStorer.
+type MockPromoStorerMockRecorder struct {
+	mock *MockPromoStorer
+}
+
+// NewMockPromoStorer creates a new mock instance.
+func NewMockPromoStorer(ctrl *gomock.Controller) *MockPromoStorer {
+	mock := &MockPromoStorer{ctrl: ctrl}
+	mock.recorder = &MockPromoStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPromoStorer) EXPECT() *MockPromoStorerMockRecorder {
+	return m.recorder
+}
+
+// AddPromoCode mocks base method.
+func (m *MockPromoStorer) AddPromoCode(arg0 context.Context, arg1 models.PromoCodeModel) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPromoCode", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddPromoCode indicates an expected call of AddPromoCode.
+func (mr *MockPromoStorerMockRecorder) AddPromoCode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPromoCode", reflect.TypeOf((*MockPromoStorer)(nil).AddPromoCode), arg0, arg1)
+}
+
+// RedeemPromoCode mocks base method.
+func (m *MockPromoStorer) RedeemPromoCode(arg0 context.Context, arg1 string, arg2 int64, arg3 time.Time) (*models.PromoCodeModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RedeemPromoCode", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*models.PromoCodeModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RedeemPromoCode indicates an expected call of RedeemPromoCode.
+func (mr *MockPromoStorerMockRecorder) RedeemPromoCode(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeemPromoCode", reflect.TypeOf((*MockPromoStorer)(nil).RedeemPromoCode), arg0, arg1, arg2, arg3)
+}
@@ -0,0 +1,430 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ShvetsovYura/oygophermart/internal/router (interfaces: Tokener,OrderWorker,UserWorker,PromoWorker)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	io "io"
+	reflect "reflect"
+
+	models "github.com/ShvetsovYura/oygophermart/internal/models"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockTokener is a mock of Tokener interface.
+type MockTokener struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenerMockRecorder
+}
+
+// MockTokenerMockRecorder is the mock recorder for MockTokener.
+type MockTokenerMockRecorder struct {
+	mock *MockTokener
+}
+
+// NewMockTokener creates a new mock instance.
+func NewMockTokener(ctrl *gomock.Controller) *MockTokener {
+	mock := &MockTokener{ctrl: ctrl}
+	mock.recorder = &MockTokenerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokener) EXPECT() *MockTokenerMockRecorder {
+	return m.recorder
+}
+
+// ExtractUserID mocks base method.
+func (m *MockTokener) ExtractUserID(arg0 string) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExtractUserID", arg0)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExtractUserID indicates an expected call of ExtractUserID.
+func (mr *MockTokenerMockRecorder) ExtractUserID(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtractUserID", reflect.TypeOf((*MockTokener)(nil).ExtractUserID), arg0)
+}
+
+// GenerateToken mocks base method.
+func (m *MockTokener) GenerateToken(arg0 uint64) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateToken", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GenerateToken indicates an expected call of GenerateToken.
+func (mr *MockTokenerMockRecorder) GenerateToken(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateToken", reflect.TypeOf((*MockTokener)(nil).GenerateToken), arg0)
+}
+
+// ValidateSign mocks base method.
+func (m *MockTokener) ValidateSign(arg0 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateSign", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ValidateSign indicates an expected call of ValidateSign.
+func (mr *MockTokenerMockRecorder) ValidateSign(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateSign", reflect.TypeOf((*MockTokener)(nil).ValidateSign), arg0)
+}
+
+// MockOrderWorker is a mock of OrderWorker interface.
+type MockOrderWorker struct {
+	ctrl     *gomock.Controller
+	recorder *MockOrderWorkerMockRecorder
+}
+
+// MockOrderWorkerMockRecorder is the mock recorder for MockOrderWorker.
+type MockOrderWorkerMockRecorder struct {
+	mock *MockOrderWorker
+}
+
+// NewMockOrderWorker creates a new mock instance.
+func NewMockOrderWorker(ctrl *gomock.Controller) *MockOrderWorker {
+	mock := &MockOrderWorker{ctrl: ctrl}
+	mock.recorder = &MockOrderWorkerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOrderWorker) EXPECT() *MockOrderWorkerMockRecorder {
+	return m.recorder
+}
+
+// CancelOrder mocks base method.
+func (m *MockOrderWorker) CancelOrder(arg0 context.Context, arg1 uint64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelOrder", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelOrder indicates an expected call of CancelOrder.
+func (mr *MockOrderWorkerMockRecorder) CancelOrder(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelOrder", reflect.TypeOf((*MockOrderWorker)(nil).CancelOrder), arg0, arg1, arg2)
+}
+
+// CreateOrder mocks base method.
+func (m *MockOrderWorker) CreateOrder(arg0 context.Context, arg1 uint64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrder", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOrder indicates an expected call of CreateOrder.
+func (mr *MockOrderWorkerMockRecorder) CreateOrder(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrder", reflect.TypeOf((*MockOrderWorker)(nil).CreateOrder), arg0, arg1, arg2)
+}
+
+// CreateOrders mocks base method.
+func (m *MockOrderWorker) CreateOrders(arg0 context.Context, arg1 uint64, arg2 []string) ([]models.BulkOrderResp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrders", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.BulkOrderResp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOrders indicates an expected call of CreateOrders.
+func (mr *MockOrderWorkerMockRecorder) CreateOrders(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrders", reflect.TypeOf((*MockOrderWorker)(nil).CreateOrders), arg0, arg1, arg2)
+}
+
+// ExportUserData mocks base method.
+func (m *MockOrderWorker) ExportUserData(arg0 context.Context, arg1 uint64, arg2, arg3 string, arg4 io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportUserData", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportUserData indicates an expected call of ExportUserData.
+func (mr *MockOrderWorkerMockRecorder) ExportUserData(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportUserData", reflect.TypeOf((*MockOrderWorker)(nil).ExportUserData), arg0, arg1, arg2, arg3, arg4)
+}
+
+// GetUserBalance mocks base method.
+func (m *MockOrderWorker) GetUserBalance(arg0 context.Context, arg1 uint64) models.BalanceModel {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserBalance", arg0, arg1)
+	ret0, _ := ret[0].(models.BalanceModel)
+	return ret0
+}
+
+// GetUserBalance indicates an expected call of GetUserBalance.
+func (mr *MockOrderWorkerMockRecorder) GetUserBalance(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserBalance", reflect.TypeOf((*MockOrderWorker)(nil).GetUserBalance), arg0, arg1)
+}
+
+// GetUserOrder mocks base method.
+func (m *MockOrderWorker) GetUserOrder(arg0 context.Context, arg1 uint64, arg2 string) (*models.OrderDetailResp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserOrder", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.OrderDetailResp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserOrder indicates an expected call of GetUserOrder.
+func (mr *MockOrderWorkerMockRecorder) GetUserOrder(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserOrder", reflect.TypeOf((*MockOrderWorker)(nil).GetUserOrder), arg0, arg1, arg2)
+}
+
+// GetUserOrders mocks base method.
+func (m *MockOrderWorker) GetUserOrders(arg0 context.Context, arg1 uint64, arg2 models.OrderQuery) ([]models.OrderGroupedModel, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserOrders", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.OrderGroupedModel)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetUserOrders indicates an expected call of GetUserOrders.
+func (mr *MockOrderWorkerMockRecorder) GetUserOrders(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserOrders", reflect.TypeOf((*MockOrderWorker)(nil).GetUserOrders), arg0, arg1, arg2)
+}
+
+// ImportOrders mocks base method.
+func (m *MockOrderWorker) ImportOrders(arg0 context.Context, arg1 io.Reader, arg2 bool) (*models.ImportResp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportOrders", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.ImportResp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportOrders indicates an expected call of ImportOrders.
+func (mr *MockOrderWorkerMockRecorder) ImportOrders(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportOrders", reflect.TypeOf((*MockOrderWorker)(nil).ImportOrders), arg0, arg1, arg2)
+}
+
+// Transfer mocks base method.
+func (m *MockOrderWorker) Transfer(arg0 context.Context, arg1 uint64, arg2 string, arg3 float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockOrderWorkerMockRecorder) Transfer(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockOrderWorker)(nil).Transfer), arg0, arg1, arg2, arg3)
+}
+
+// UserHistory mocks base method.
+func (m *MockOrderWorker) UserHistory(arg0 context.Context, arg1 uint64, arg2 models.HistoryQuery) (*models.HistoryResp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserHistory", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.HistoryResp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserHistory indicates an expected call of UserHistory.
+func (mr *MockOrderWorkerMockRecorder) UserHistory(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserHistory", reflect.TypeOf((*MockOrderWorker)(nil).UserHistory), arg0, arg1, arg2)
+}
+
+// UserTransfers mocks base method.
+func (m *MockOrderWorker) UserTransfers(arg0 context.Context, arg1 uint64) ([]models.TransferResp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserTransfers", arg0, arg1)
+	ret0, _ := ret[0].([]models.TransferResp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserTransfers indicates an expected call of UserTransfers.
+func (mr *MockOrderWorkerMockRecorder) UserTransfers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserTransfers", reflect.TypeOf((*MockOrderWorker)(nil).UserTransfers), arg0, arg1)
+}
+
+// UserWithdrawals mocks base method.
+func (m *MockOrderWorker) UserWithdrawals(arg0 context.Context, arg1 uint64) ([]models.OrderGroupedModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserWithdrawals", arg0, arg1)
+	ret0, _ := ret[0].([]models.OrderGroupedModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserWithdrawals indicates an expected call of UserWithdrawals.
+func (mr *MockOrderWorkerMockRecorder) UserWithdrawals(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserWithdrawals", reflect.TypeOf((*MockOrderWorker)(nil).UserWithdrawals), arg0, arg1)
+}
+
+// Withdraw mocks base method.
+func (m *MockOrderWorker) Withdraw(arg0 context.Context, arg1 uint64, arg2 string, arg3 float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Withdraw", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Withdraw indicates an expected call of Withdraw.
+func (mr *MockOrderWorkerMockRecorder) Withdraw(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockOrderWorker)(nil).Withdraw), arg0, arg1, arg2, arg3)
+}
+
+// MockUserWorker is a mock of UserWorker interface.
+type MockUserWorker struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserWorkerMockRecorder
+}
+
+// MockUserWorkerMockRecorder is the mock recorder for MockUserWorker.
+type MockUserWorkerMockRecorder struct {
+	mock *MockUserWorker
+}
+
+// NewMockUserWorker creates a new mock instance.
+func NewMockUserWorker(ctrl *gomock.Controller) *MockUserWorker {
+	mock := &MockUserWorker{ctrl: ctrl}
+	mock.recorder = &MockUserWorkerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserWorker) EXPECT() *MockUserWorkerMockRecorder {
+	return m.recorder
+}
+
+// CreateUser mocks base method.
+func (m *MockUserWorker) CreateUser(arg0 context.Context, arg1, arg2, arg3, arg4 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockUserWorkerMockRecorder) CreateUser(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserWorker)(nil).CreateUser), arg0, arg1, arg2, arg3, arg4)
+}
+
+// GetReferralInfo mocks base method.
+func (m *MockUserWorker) GetReferralInfo(arg0 context.Context, arg1 uint64) (*models.ReferralResp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReferralInfo", arg0, arg1)
+	ret0, _ := ret[0].(*models.ReferralResp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReferralInfo indicates an expected call of GetReferralInfo.
+func (mr *MockUserWorkerMockRecorder) GetReferralInfo(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReferralInfo", reflect.TypeOf((*MockUserWorker)(nil).GetReferralInfo), arg0, arg1)
+}
+
+// IsAdmin mocks base method.
+func (m *MockUserWorker) IsAdmin(arg0 context.Context, arg1 uint64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsAdmin", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsAdmin indicates an expected call of IsAdmin.
+func (mr *MockUserWorkerMockRecorder) IsAdmin(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsAdmin", reflect.TypeOf((*MockUserWorker)(nil).IsAdmin), arg0, arg1)
+}
+
+// Login mocks base method.
+func (m *MockUserWorker) Login(arg0 context.Context, arg1, arg2 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Login", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Login indicates an expected call of Login.
+func (mr *MockUserWorkerMockRecorder) Login(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockUserWorker)(nil).Login), arg0, arg1, arg2)
+}
+
+// MockPromoWorker is a mock of PromoWorker interface.
+type MockPromoWorker struct {
+	ctrl     *gomock.Controller
+	recorder *MockPromoWorkerMockRecorder
+}
+
+// MockPromoWorkerMockRecorder is the mock recorder for MockPromoWorker.
+type MockPromoWorkerMockRecorder struct {
+	mock *MockPromoWorker
+}
+
+// NewMockPromoWorker creates a new mock instance.
+func NewMockPromoWorker(ctrl *gomock.Controller) *MockPromoWorker {
+	mock := &MockPromoWorker{ctrl: ctrl}
+	mock.recorder = &MockPromoWorkerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPromoWorker) EXPECT() *MockPromoWorkerMockRecorder {
+	return m.recorder
+}
+
+// CreatePromo mocks base method.
+func (m *MockPromoWorker) CreatePromo(arg0 context.Context, arg1 uint64, arg2 models.PromoReq) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePromo", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePromo indicates an expected call of CreatePromo.
+func (mr *MockPromoWorkerMockRecorder) CreatePromo(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePromo", reflect.TypeOf((*MockPromoWorker)(nil).CreatePromo), arg0, arg1, arg2)
+}
+
+// RedeemPromo mocks base method.
+func (m *MockPromoWorker) RedeemPromo(arg0 context.Context, arg1 uint64, arg2 string) (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RedeemPromo", arg0, arg1, arg2)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RedeemPromo indicates an expected call of RedeemPromo.
+func (mr *MockPromoWorkerMockRecorder) RedeemPromo(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeemPromo", reflect.TypeOf((*MockPromoWorker)(nil).RedeemPromo), arg0, arg1, arg2)
+}
@@ -0,0 +1,109 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ShvetsovYura/oygophermart/internal/accrual_agent (interfaces: Saver)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	models "github.com/ShvetsovYura/oygophermart/internal/models"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSaver is a mock of Saver interface.
+type MockSaver struct {
+	ctrl     *gomock.Controller
+	recorder *MockSaverMockRecorder
+}
+
+// MockSaverMockRecorder is the mock recorder for MockSaver.
+type MockSaverMockRecorder struct {
+	mock *MockSaver
+}
+
+// NewMockSaver creates a new mock instance.
+func NewMockSaver(ctrl *gomock.Controller) *MockSaver {
+	mock := &MockSaver{ctrl: ctrl}
+	mock.recorder = &MockSaverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSaver) EXPECT() *MockSaverMockRecorder {
+	return m.recorder
+}
+
+// AssignOrdersPartner mocks base method.
+func (m *MockSaver) AssignOrdersPartner(arg0 context.Context, arg1 string, arg2 ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "AssignOrdersPartner", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AssignOrdersPartner indicates an expected call of AssignOrdersPartner.
+func (mr *MockSaverMockRecorder) AssignOrdersPartner(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignOrdersPartner", reflect.TypeOf((*MockSaver)(nil).AssignOrdersPartner), varargs...)
+}
+
+// ClaimAccrualJobs mocks base method.
+func (m *MockSaver) ClaimAccrualJobs(arg0 context.Context, arg1 int, arg2 time.Duration) ([]models.AccrualJobModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimAccrualJobs", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.AccrualJobModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimAccrualJobs indicates an expected call of ClaimAccrualJobs.
+func (mr *MockSaverMockRecorder) ClaimAccrualJobs(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimAccrualJobs", reflect.TypeOf((*MockSaver)(nil).ClaimAccrualJobs), arg0, arg1, arg2)
+}
+
+// FailAccrualJobs mocks base method.
+func (m *MockSaver) FailAccrualJobs(arg0 context.Context, arg1 ...models.AccrualJobFailure) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "FailAccrualJobs", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FailAccrualJobs indicates an expected call of FailAccrualJobs.
+func (mr *MockSaverMockRecorder) FailAccrualJobs(arg0 interface{}, arg1 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailAccrualJobs", reflect.TypeOf((*MockSaver)(nil).FailAccrualJobs), varargs...)
+}
+
+// UpdateOrdersStatus mocks base method.
+func (m *MockSaver) UpdateOrdersStatus(arg0 context.Context, arg1 ...models.AccrualResult) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateOrdersStatus", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOrdersStatus indicates an expected call of UpdateOrdersStatus.
+func (mr *MockSaverMockRecorder) UpdateOrdersStatus(arg0 interface{}, arg1 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrdersStatus", reflect.TypeOf((*MockSaver)(nil).UpdateOrdersStatus), varargs...)
+}
@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/ShvetsovYura/oygophermart/internal/services (interfaces: OrderStorer)
+// Source: github.com/ShvetsovYura/oygophermart/internal/services (interfaces: OrderStorer,UserStorer)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -36,54 +36,127 @@ func (m *MockOrderStorer) EXPECT() *MockOrderStorerMockRecorder {
 }
 
 // AddNewOrder mocks base method.
-func (m *MockOrderStorer) AddNewOrder(arg0 context.Context, arg1 int64, arg2, arg3 string, arg4 int64) error {
+func (m *MockOrderStorer) AddNewOrder(arg0 context.Context, arg1 int64, arg2 string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddNewOrder", arg0, arg1, arg2, arg3, arg4)
+	ret := m.ctrl.Call(m, "AddNewOrder", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // AddNewOrder indicates an expected call of AddNewOrder.
-func (mr *MockOrderStorerMockRecorder) AddNewOrder(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+func (mr *MockOrderStorerMockRecorder) AddNewOrder(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNewOrder", reflect.TypeOf((*MockOrderStorer)(nil).AddNewOrder), arg0, arg1, arg2, arg3, arg4)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNewOrder", reflect.TypeOf((*MockOrderStorer)(nil).AddNewOrder), arg0, arg1, arg2)
 }
 
-// GetOrdersById mocks base method.
-func (m *MockOrderStorer) GetOrdersByID(arg0 context.Context, arg1 string) ([]models.LoyaltyOrderModel, error) {
+// AddNewOrders mocks base method.
+func (m *MockOrderStorer) AddNewOrders(arg0 context.Context, arg1 int64, arg2 []string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddNewOrders", arg0, arg1, arg2)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddNewOrders indicates an expected call of AddNewOrders.
+func (mr *MockOrderStorerMockRecorder) AddNewOrders(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNewOrders", reflect.TypeOf((*MockOrderStorer)(nil).AddNewOrders), arg0, arg1, arg2)
+}
+
+// CancelOrder mocks base method.
+func (m *MockOrderStorer) CancelOrder(arg0 context.Context, arg1 string, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelOrder", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelOrder indicates an expected call of CancelOrder.
+func (mr *MockOrderStorerMockRecorder) CancelOrder(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelOrder", reflect.TypeOf((*MockOrderStorer)(nil).CancelOrder), arg0, arg1, arg2)
+}
+
+// GetOrderPolls mocks base method.
+func (m *MockOrderStorer) GetOrderPolls(arg0 context.Context, arg1 string) ([]models.OrderPollModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderPolls", arg0, arg1)
+	ret0, _ := ret[0].([]models.OrderPollModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderPolls indicates an expected call of GetOrderPolls.
+func (mr *MockOrderStorerMockRecorder) GetOrderPolls(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderPolls", reflect.TypeOf((*MockOrderStorer)(nil).GetOrderPolls), arg0, arg1)
+}
+
+// GetOrdersByID mocks base method.
+func (m *MockOrderStorer) GetOrdersByID(arg0 context.Context, arg1 string) ([]models.OrderModel, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetOrdersByID", arg0, arg1)
-	ret0, _ := ret[0].([]models.LoyaltyOrderModel)
+	ret0, _ := ret[0].([]models.OrderModel)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetOrdersById indicates an expected call of GetOrdersById.
+// GetOrdersByID indicates an expected call of GetOrdersByID.
 func (mr *MockOrderStorerMockRecorder) GetOrdersByID(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrdersByID", reflect.TypeOf((*MockOrderStorer)(nil).GetOrdersByID), arg0, arg1)
 }
 
-// GetUserByLogin mocks base method.
-func (m *MockOrderStorer) GetUserByLogin(arg0 context.Context, arg1 string) (*models.UserModel, error) {
+// GetUserBalance mocks base method.
+func (m *MockOrderStorer) GetUserBalance(arg0 context.Context, arg1 uint64) models.BalanceModel {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserByLogin", arg0, arg1)
-	ret0, _ := ret[0].(*models.UserModel)
+	ret := m.ctrl.Call(m, "GetUserBalance", arg0, arg1)
+	ret0, _ := ret[0].(models.BalanceModel)
+	return ret0
+}
+
+// GetUserBalance indicates an expected call of GetUserBalance.
+func (mr *MockOrderStorerMockRecorder) GetUserBalance(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserBalance", reflect.TypeOf((*MockOrderStorer)(nil).GetUserBalance), arg0, arg1)
+}
+
+// GetUserHistory mocks base method.
+func (m *MockOrderStorer) GetUserHistory(arg0 context.Context, arg1 int64, arg2 models.HistoryFilter) ([]models.HistoryRecordModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserHistory", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.HistoryRecordModel)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserByLogin indicates an expected call of GetUserByLogin.
-func (mr *MockOrderStorerMockRecorder) GetUserByLogin(arg0, arg1 interface{}) *gomock.Call {
+// GetUserHistory indicates an expected call of GetUserHistory.
+func (mr *MockOrderStorerMockRecorder) GetUserHistory(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByLogin", reflect.TypeOf((*MockOrderStorer)(nil).GetUserByLogin), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserHistory", reflect.TypeOf((*MockOrderStorer)(nil).GetUserHistory), arg0, arg1, arg2)
+}
+
+// GetUserOrderByID mocks base method.
+func (m *MockOrderStorer) GetUserOrderByID(arg0 context.Context, arg1 string, arg2 int64) (*models.OrderDetailModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserOrderByID", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.OrderDetailModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserOrderByID indicates an expected call of GetUserOrderByID.
+func (mr *MockOrderStorerMockRecorder) GetUserOrderByID(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserOrderByID", reflect.TypeOf((*MockOrderStorer)(nil).GetUserOrderByID), arg0, arg1, arg2)
 }
 
 // GetUserOrders mocks base method.
-func (m *MockOrderStorer) GetUserOrders(arg0 context.Context, arg1 string) ([]models.LoyaltyOrderModel, error) {
+func (m *MockOrderStorer) GetUserOrders(arg0 context.Context, arg1 uint64) ([]models.OrderGroupedModel, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetUserOrders", arg0, arg1)
-	ret0, _ := ret[0].([]models.LoyaltyOrderModel)
+	ret0, _ := ret[0].([]models.OrderGroupedModel)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -93,3 +166,188 @@ func (mr *MockOrderStorerMockRecorder) GetUserOrders(arg0, arg1 interface{}) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserOrders", reflect.TypeOf((*MockOrderStorer)(nil).GetUserOrders), arg0, arg1)
 }
+
+// GetUserOrdersPage mocks base method.
+func (m *MockOrderStorer) GetUserOrdersPage(arg0 context.Context, arg1 uint64, arg2 models.OrderFilter) ([]models.OrderGroupedModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserOrdersPage", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.OrderGroupedModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserOrdersPage indicates an expected call of GetUserOrdersPage.
+func (mr *MockOrderStorerMockRecorder) GetUserOrdersPage(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserOrdersPage", reflect.TypeOf((*MockOrderStorer)(nil).GetUserOrdersPage), arg0, arg1, arg2)
+}
+
+// GetUserTransfers mocks base method.
+func (m *MockOrderStorer) GetUserTransfers(arg0 context.Context, arg1 int64) ([]models.TransferModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserTransfers", arg0, arg1)
+	ret0, _ := ret[0].([]models.TransferModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserTransfers indicates an expected call of GetUserTransfers.
+func (mr *MockOrderStorerMockRecorder) GetUserTransfers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserTransfers", reflect.TypeOf((*MockOrderStorer)(nil).GetUserTransfers), arg0, arg1)
+}
+
+// ImportOrders mocks base method.
+func (m *MockOrderStorer) ImportOrders(arg0 context.Context, arg1 []models.ImportOrderModel, arg2 bool) ([]models.ImportErrorModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportOrders", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.ImportErrorModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportOrders indicates an expected call of ImportOrders.
+func (mr *MockOrderStorerMockRecorder) ImportOrders(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportOrders", reflect.TypeOf((*MockOrderStorer)(nil).ImportOrders), arg0, arg1, arg2)
+}
+
+// Transfer mocks base method.
+func (m *MockOrderStorer) Transfer(arg0 context.Context, arg1, arg2 int64, arg3 float64, arg4 models.TransferLimits) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockOrderStorerMockRecorder) Transfer(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockOrderStorer)(nil).Transfer), arg0, arg1, arg2, arg3, arg4)
+}
+
+// Withdraw mocks base method.
+func (m *MockOrderStorer) Withdraw(arg0 context.Context, arg1 string, arg2 int64, arg3 float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Withdraw", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Withdraw indicates an expected call of Withdraw.
+func (mr *MockOrderStorerMockRecorder) Withdraw(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockOrderStorer)(nil).Withdraw), arg0, arg1, arg2, arg3)
+}
+
+// MockUserStorer is a mock of UserStorer interface.
+type MockUserStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserStorerMockRecorder
+}
+
+// MockUserStorerMockRecorder is the mock recorder for MockUserStorer.
+type MockUserStorerMockRecorder struct {
+	mock *MockUserStorer
+}
+
+// NewMockUserStorer creates a new mock instance.
+func NewMockUserStorer(ctrl *gomock.Controller) *MockUserStorer {
+	mock := &MockUserStorer{ctrl: ctrl}
+	mock.recorder = &MockUserStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserStorer) EXPECT() *MockUserStorerMockRecorder {
+	return m.recorder
+}
+
+// AddUser mocks base method.
+func (m *MockUserStorer) AddUser(arg0 context.Context, arg1, arg2, arg3, arg4 string, arg5 *models.ReferralModel) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddUser", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddUser indicates an expected call of AddUser.
+func (mr *MockUserStorerMockRecorder) AddUser(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUser", reflect.TypeOf((*MockUserStorer)(nil).AddUser), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// CountReferrals mocks base method.
+func (m *MockUserStorer) CountReferrals(arg0 context.Context, arg1 int64) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountReferrals", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountReferrals indicates an expected call of CountReferrals.
+func (mr *MockUserStorerMockRecorder) CountReferrals(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountReferrals", reflect.TypeOf((*MockUserStorer)(nil).CountReferrals), arg0, arg1)
+}
+
+// GetReferralStats mocks base method.
+func (m *MockUserStorer) GetReferralStats(arg0 context.Context, arg1 int64) (models.ReferralStatsModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReferralStats", arg0, arg1)
+	ret0, _ := ret[0].(models.ReferralStatsModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReferralStats indicates an expected call of GetReferralStats.
+func (mr *MockUserStorerMockRecorder) GetReferralStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReferralStats", reflect.TypeOf((*MockUserStorer)(nil).GetReferralStats), arg0, arg1)
+}
+
+// GetUserByID mocks base method.
+func (m *MockUserStorer) GetUserByID(arg0 context.Context, arg1 int64) (*models.UserModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", arg0, arg1)
+	ret0, _ := ret[0].(*models.UserModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByID indicates an expected call of GetUserByID.
+func (mr *MockUserStorerMockRecorder) GetUserByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockUserStorer)(nil).GetUserByID), arg0, arg1)
+}
+
+// GetUserByLogin mocks base method.
+func (m *MockUserStorer) GetUserByLogin(arg0 context.Context, arg1 string) (*models.UserModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByLogin", arg0, arg1)
+	ret0, _ := ret[0].(*models.UserModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByLogin indicates an expected call of GetUserByLogin.
+func (mr *MockUserStorerMockRecorder) GetUserByLogin(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByLogin", reflect.TypeOf((*MockUserStorer)(nil).GetUserByLogin), arg0, arg1)
+}
+
+// GetUserByReferralCode mocks base method.
+func (m *MockUserStorer) GetUserByReferralCode(arg0 context.Context, arg1 string) (*models.UserModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByReferralCode", arg0, arg1)
+	ret0, _ := ret[0].(*models.UserModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByReferralCode indicates an expected call of GetUserByReferralCode.
+func (mr *MockUserStorerMockRecorder) GetUserByReferralCode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByReferralCode", reflect.TypeOf((*MockUserStorer)(nil).GetUserByReferralCode), arg0, arg1)
+}
@@ -0,0 +1,27 @@
+package mocks
+
+import (
+	"testing"
+
+	accrualagent "github.com/ShvetsovYura/oygophermart/internal/accrual_agent"
+	"github.com/ShvetsovYura/oygophermart/internal/router"
+	"github.com/ShvetsovYura/oygophermart/internal/services"
+)
+
+// моки должны реализовывать свои интерфейсы; после изменения интерфейса
+// тест не соберется, пока моки не перегенерированы через go generate ./...
+var (
+	_ services.OrderStorer        = (*MockOrderStorer)(nil)
+	_ services.UserStorer         = (*MockUserStorer)(nil)
+	_ services.Hasher             = (*MockHasher)(nil)
+	_ services.PromoStorer        = (*MockPromoStorer)(nil)
+	_ services.DisputeStorer      = (*MockDisputeStorer)(nil)
+	_ services.NotificationStorer = (*MockNotificationStorer)(nil)
+	_ router.Tokener              = (*MockTokener)(nil)
+	_ router.OrderWorker          = (*MockOrderWorker)(nil)
+	_ router.UserWorker           = (*MockUserWorker)(nil)
+	_ router.PromoWorker          = (*MockPromoWorker)(nil)
+	_ accrualagent.Saver          = (*MockSaver)(nil)
+)
+
+func TestMocksImplementInterfaces(t *testing.T) {}